@@ -27,7 +27,7 @@ func (b *Blob) Centre() point.Point {
 		totX += float64(p.X)
 		totY += float64(p.Y)
 	}
-	return point.Point{totX / float64(numPoints), totY / float64(numPoints)}
+	return point.Point{X: totX / float64(numPoints), Y: totY / float64(numPoints)}
 }
 
 // Find finds the blobs in an image. The input image is searched for connected
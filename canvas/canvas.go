@@ -16,6 +16,15 @@ type Context interface {
 	Render(im draw.Image)
 }
 
+// TextContext is a Context that can also draw text.
+type TextContext interface {
+	Context
+	// Text draws s with its top left corner at p in a small bitmap font,
+	// which has digits, capital letters and a few symbols. Lower case letters
+	// are drawn as capitals and other characters are left blank.
+	Text(p image.Point, s string)
+}
+
 type op interface {
 	do(i draw.Image)
 }
@@ -128,6 +137,11 @@ type context struct {
 }
 
 func New() Context {
+	return NewTextContext()
+}
+
+// NewTextContext returns a context like New that can also draw text.
+func NewTextContext() TextContext {
 	return &context{
 		c: color.White,
 		p: image.ZP,
@@ -149,6 +163,10 @@ func (c *context) Line(d, a float64) {
 	c.ops = append(c.ops, line{d, a, c.c})
 }
 
+func (c *context) Text(p image.Point, s string) {
+	c.ops = append(c.ops, text{p, s, c.c})
+}
+
 func (c *context) Render(im draw.Image) {
 	for _, op := range c.ops {
 		op.do(im)
@@ -156,8 +174,6 @@ func (c *context) Render(im draw.Image) {
 }
 
 func DrawLine(p1, p2 image.Point, col color.Color, im draw.Image) {
-	fmt.Printf("Drawing line %v %v \n", p1, p2)
-
 	// determine which way to draw the line
 	// top to bottom or left to right
 	xdiff := p2.X - p1.X
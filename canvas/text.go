@@ -0,0 +1,97 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"unicode"
+)
+
+// GlyphWidth and GlyphHeight are the size in pixels of the characters drawn
+// by Text, which are separated by a column of space.
+const (
+	GlyphWidth  = 3
+	GlyphHeight = 5
+)
+
+// glyphs holds the characters Text can draw, each row of a glyph is a 3 bit
+// mask with the leftmost pixel in the highest bit. Letters only have capitals.
+var glyphs = map[rune][GlyphHeight]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 3, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'A': {2, 5, 7, 5, 5},
+	'B': {6, 5, 6, 5, 6},
+	'C': {3, 4, 4, 4, 3},
+	'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7},
+	'F': {7, 4, 6, 4, 4},
+	'G': {3, 4, 5, 5, 3},
+	'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7},
+	'J': {1, 1, 1, 5, 2},
+	'K': {5, 5, 6, 5, 5},
+	'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5},
+	'N': {6, 5, 5, 5, 5},
+	'O': {2, 5, 5, 5, 2},
+	'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3},
+	'R': {6, 5, 6, 5, 5},
+	'S': {3, 4, 2, 1, 6},
+	'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7},
+	'V': {5, 5, 5, 5, 2},
+	'W': {5, 5, 7, 7, 5},
+	'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2},
+	'Z': {7, 1, 2, 4, 7},
+	'-': {0, 0, 7, 0, 0},
+	'+': {0, 2, 7, 2, 0},
+	'=': {0, 7, 0, 7, 0},
+	'.': {0, 0, 0, 0, 2},
+	',': {0, 0, 0, 2, 4},
+	':': {0, 2, 0, 2, 0},
+	'/': {1, 1, 2, 4, 4},
+	'(': {1, 2, 2, 2, 1},
+	')': {4, 2, 2, 2, 4},
+	'%': {5, 1, 2, 4, 5},
+	'°': {2, 5, 2, 0, 0},
+}
+
+// TextWidth returns the width in pixels of s drawn by Text.
+func TextWidth(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return n*(GlyphWidth+1) - 1
+}
+
+type text struct {
+	p image.Point
+	s string
+	c color.Color
+}
+
+func (t text) do(im draw.Image) {
+	x := t.p.X
+	for _, r := range t.s {
+		g := glyphs[unicode.ToUpper(r)]
+		for row, bits := range g {
+			for col := 0; col < GlyphWidth; col++ {
+				if bits&(1<<uint(GlyphWidth-1-col)) != 0 {
+					im.Set(x+col, t.p.Y+row, t.c)
+				}
+			}
+		}
+		x += GlyphWidth + 1
+	}
+}
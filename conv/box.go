@@ -0,0 +1,78 @@
+package conv
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/integral"
+)
+
+// BoxFilter returns input with each pixel replaced by the mean of the square
+// window of the given radius around it. Windows are clipped to the image, so
+// pixels near the edges are averaged over fewer pixels. The cost per pixel
+// does not depend on the radius.
+func BoxFilter(input *gray16.Gray16, radius int) (*gray16.Gray16, error) {
+	if radius < 0 {
+		return nil, fmt.Errorf("conv: invalid radius %d", radius)
+	}
+	t := integral.New(input)
+	return local(input, func(p image.Point, v uint16) uint16 {
+		return uint16(t.Mean(integral.Window(p, radius)) + 0.5)
+	}), nil
+}
+
+// AdaptiveThreshBox returns an image that is white where a pixel of input is
+// more than c above the mean of the square window of the given radius around
+// it and black elsewhere. It generalises AdaptiveThresh to windows of any
+// size at a cost per pixel that does not depend on the radius.
+func AdaptiveThreshBox(input *gray16.Gray16, radius int, c float64) (*gray16.Gray16, error) {
+	if radius < 0 {
+		return nil, fmt.Errorf("conv: invalid radius %d", radius)
+	}
+	t := integral.New(input)
+	return local(input, func(p image.Point, v uint16) uint16 {
+		if float64(v) > t.Mean(integral.Window(p, radius))+c {
+			return math.MaxUint16
+		}
+		return 0
+	}), nil
+}
+
+// Sauvola returns an image that is white where a pixel of input is at or
+// below the threshold of Sauvola and Pietikäinen, m*(1 + k*(s/R - 1)) for the
+// mean m and standard deviation s of the square window of the given radius
+// around it and R half the range of values, and black elsewhere. It finds dark
+// foreground, such as text, on a background of uneven brightness, and keeps
+// noise in flat regions out of the foreground better than a threshold
+// relative to the mean alone. A k of 0.2 to 0.5 is typical.
+func Sauvola(input *gray16.Gray16, radius int, k float64) (*gray16.Gray16, error) {
+	if radius < 0 {
+		return nil, fmt.Errorf("conv: invalid radius %d", radius)
+	}
+	sum, squared := integral.New(input), integral.NewSquared(input)
+	const r = (math.MaxUint16 + 1) / 2
+	return local(input, func(p image.Point, v uint16) uint16 {
+		w := integral.Window(p, radius)
+		t := sum.Mean(w) * (1 + k*(integral.StdDev(sum, squared, w)/r-1))
+		if float64(v) <= t {
+			return math.MaxUint16
+		}
+		return 0
+	}), nil
+}
+
+// local returns the image of f applied to each pixel of input.
+func local(input *gray16.Gray16, f func(p image.Point, v uint16) uint16) *gray16.Gray16 {
+	b := input.Rect
+	output := gray16.NewGray16(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i, o := input.PixOffset(b.Min.X, y), output.PixOffset(b.Min.X, y)
+		for x, v := range input.Pix[i : i+b.Dx()] {
+			output.Pix[o+x] = f(image.Pt(b.Min.X+x, y), v)
+		}
+	}
+	output.UpdateRange()
+	return output
+}
@@ -0,0 +1,60 @@
+package conv
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+func TestBoxFilter(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(0, 0, 5, 1))
+	copy(g.Pix, []uint16{0, 300, 0, 0, 900})
+	out, err := BoxFilter(g, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range []uint16{150, 100, 100, 300, 450} {
+		if out.Pix[i] != e {
+			t.Errorf("Expecting %d at %d got %d", e, i, out.Pix[i])
+		}
+	}
+	if _, err := BoxFilter(g, -1); err == nil {
+		t.Error("Expecting an error for a negative radius")
+	}
+}
+
+// gradient returns a horizontal gradient with a spot of the given value at
+// its centre.
+func gradient(spot uint16) *gray16.Gray16 {
+	g := gray16.NewGray16(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			g.Pix[g.PixOffset(x, y)] = uint16(5000 + x*1000)
+		}
+	}
+	g.Pix[g.PixOffset(20, 20)] = spot
+	g.UpdateRange()
+	return g
+}
+
+func TestLocalThresholds(t *testing.T) {
+	// Only the spot stands out from its neighbourhood.
+	box, err := AdaptiveThreshBox(gradient(math.MaxUint16), 5, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sauvola, err := Sauvola(gradient(0), 5, 0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]*gray16.Gray16{"box": box, "sauvola": sauvola} {
+		if out.Gray16At(20, 20).Y != math.MaxUint16 {
+			t.Errorf("%s: expecting the spot to be found", name)
+		}
+		if out.Gray16At(10, 10).Y != 0 || out.Gray16At(30, 5).Y != 0 {
+			t.Errorf("%s: expecting the gradient to be background", name)
+		}
+	}
+}
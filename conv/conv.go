@@ -8,11 +8,6 @@ import (
 	"github.com/piersy/hough-go/gray16"
 )
 
-type kernel struct {
-	k      []uint16
-	stride uint16
-}
-
 func AdaptiveThresh(input *gray16.Gray16) *gray16.Gray16 {
 	c := float64(math.MaxUint16 / 2)
 	max := color.Gray16{math.MaxUint16}
@@ -35,5 +30,6 @@ func AdaptiveThresh(input *gray16.Gray16) *gray16.Gray16 {
 			}
 		}
 	}
+	output.UpdateRange()
 	return output
 }
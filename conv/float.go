@@ -0,0 +1,105 @@
+package conv
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/piersy/hough-go/gray32f"
+)
+
+// Kernel is a convolution kernel of Width by Height weights in row order,
+// centred on the weight at (Width/2, Height/2).
+type Kernel struct {
+	K             []float32
+	Width, Height int
+}
+
+// NewKernel returns a kernel of the given weights, which must have width times
+// height elements.
+func NewKernel(width, height int, k ...float32) (Kernel, error) {
+	if width < 1 || height < 1 || len(k) != width*height {
+		return Kernel{}, fmt.Errorf("conv: %d weights for a %dx%d kernel", len(k), width, height)
+	}
+	return Kernel{K: k, Width: width, Height: height}, nil
+}
+
+// Sobel kernels of the horizontal and vertical gradients.
+var (
+	SobelX = Kernel{K: []float32{-1, 0, 1, -2, 0, 2, -1, 0, 1}, Width: 3, Height: 3}
+	SobelY = Kernel{K: []float32{-1, -2, -1, 0, 0, 0, 1, 2, 1}, Width: 3, Height: 3}
+)
+
+// Convolve returns the correlation of input with the kernel, the kernel is
+// not flipped. Pixels beyond the edges of the input repeat the nearest edge
+// pixel.
+func Convolve(input *gray32f.Gray32f, k Kernel) *gray32f.Gray32f {
+	b := input.Rect
+	output := gray32f.NewGray32f(b)
+	if b.Empty() {
+		return output
+	}
+	cx, cy := k.Width/2, k.Height/2
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var sum float32
+			for j := 0; j < k.Height; j++ {
+				sy := clamp(y+j-cy, b.Min.Y, b.Max.Y-1)
+				row := input.PixOffset(b.Min.X, sy) - b.Min.X
+				for i, w := range k.K[j*k.Width : (j+1)*k.Width] {
+					sum += w * input.Pix[row+clamp(x+i-cx, b.Min.X, b.Max.X-1)]
+				}
+			}
+			output.Pix[output.PixOffset(x, y)] = sum
+		}
+	}
+	return output
+}
+
+// Gaussian returns a normalised one dimensional Gaussian kernel of the given
+// standard deviation, reaching three deviations either side of its centre.
+func Gaussian(sigma float64) (Kernel, error) {
+	if !(sigma > 0) || math.IsInf(sigma, 1) {
+		return Kernel{}, fmt.Errorf("conv: invalid sigma %g", sigma)
+	}
+	r := int(math.Ceil(3 * sigma))
+	k := make([]float32, 2*r+1)
+	var sum float64
+	w := make([]float64, len(k))
+	for i := range w {
+		d := float64(i - r)
+		w[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += w[i]
+	}
+	for i := range k {
+		k[i] = float32(w[i] / sum)
+	}
+	return Kernel{K: k, Width: len(k), Height: 1}, nil
+}
+
+// GaussianBlur returns input blurred by a Gaussian of the given standard
+// deviation, applied separably along the rows and then the columns.
+func GaussianBlur(input *gray32f.Gray32f, sigma float64) (*gray32f.Gray32f, error) {
+	k, err := Gaussian(sigma)
+	if err != nil {
+		return nil, err
+	}
+	rows := Convolve(input, k)
+	k.Width, k.Height = k.Height, k.Width
+	return Convolve(rows, k), nil
+}
+
+// Sobel returns the horizontal and vertical gradients of input, positive
+// where values increase to the right and downwards.
+func Sobel(input *gray32f.Gray32f) (gx, gy *gray32f.Gray32f) {
+	return Convolve(input, SobelX), Convolve(input, SobelY)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package conv
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/gray32f"
+)
+
+func TestSobel(t *testing.T) {
+	// A step from 0 to 1 between columns 1 and 2.
+	p := gray32f.NewGray32f(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		p.SetGray32f(2, y, gray32f.Color{Y: 1})
+		p.SetGray32f(3, y, gray32f.Color{Y: 1})
+	}
+	gx, gy := Sobel(p)
+	for y := 0; y < 3; y++ {
+		for x, e := range []float32{0, 4, 4, 0} {
+			if v := gx.Gray32fAt(x, y).Y; v != e {
+				t.Errorf("Expecting gx %g at (%d, %d) got %g", e, x, y, v)
+			}
+			if v := gy.Gray32fAt(x, y).Y; v != 0 {
+				t.Errorf("Expecting gy 0 at (%d, %d) got %g", x, y, v)
+			}
+		}
+	}
+}
+
+func TestGaussianBlur(t *testing.T) {
+	if _, err := GaussianBlur(gray32f.NewGray32f(image.Rect(0, 0, 1, 1)), 0); err == nil {
+		t.Error("Expecting an error for a sigma of 0")
+	}
+	p := gray32f.NewGray32f(image.Rect(10, 10, 31, 31))
+	p.SetGray32f(20, 20, gray32f.Color{Y: 1})
+	out, err := GaussianBlur(p, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, v := range out.Pix {
+		sum += float64(v)
+	}
+	if math.Abs(sum-1) > 1e-4 {
+		t.Errorf("Expecting the blur to preserve the total got %g", sum)
+	}
+	centre := out.Gray32fAt(20, 20).Y
+	if e := 1 / (2 * math.Pi * 1.5 * 1.5); math.Abs(float64(centre)-e) > 0.005 {
+		t.Errorf("Expecting the centre near %g got %g", e, centre)
+	}
+	if out.Gray32fAt(21, 20).Y != out.Gray32fAt(20, 21).Y || out.Gray32fAt(21, 20).Y >= centre {
+		t.Error("Expecting a symmetric peak at the centre")
+	}
+}
@@ -0,0 +1,153 @@
+// Package deskew estimates and corrects the skew of scanned documents, such
+// as pages of text, using the hough transform.
+package deskew
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/hough"
+	"github.com/piersy/hough-go/warp"
+)
+
+// Option configures the skew estimate.
+type Option func(*config)
+
+type config struct {
+	maxSkew, precision float64
+	threshold          uint32
+}
+
+// MaxSkew sets the largest skew, in radians either side of upright, that is
+// searched for. The default is 15 degrees.
+func MaxSkew(a float64) Option {
+	return func(c *config) {
+		c.maxSkew = a
+	}
+}
+
+// Precision sets the angular step, in radians, of the search. The estimate
+// is interpolated between steps so is usually finer than this. The default
+// is 0.05 degrees.
+func Precision(a float64) Option {
+	return func(c *config) {
+		c.precision = a
+	}
+}
+
+// Threshold sets the luminance, in the range [0, 0xffff], below which
+// pixels are considered to be part of the text. The default is 0x8000.
+func Threshold(t uint32) Option {
+	return func(c *config) {
+		c.threshold = t
+	}
+}
+
+// perPixel is the number of distance buckets per pixel of the transform
+// used to score the angles.
+const perPixel = 4
+
+// Angle estimates the skew of the text in im. The text lines are found as the
+// lines of the dominant angle in the hough transform of the dark pixels. The
+// returned angle is in radians, a positive angle means the lines descend from
+// left to right, which appears as a clockwise rotation since y increases down
+// the image.
+func Angle(im image.Image, opts ...Option) (float64, error) {
+	c := config{
+		maxSkew:   15 * math.Pi / 180,
+		precision: 0.05 * math.Pi / 180,
+		threshold: 0x8000,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	if !(c.maxSkew > 0 && c.maxSkew < math.Pi/2) {
+		return 0, fmt.Errorf("deskew: invalid max skew %g", c.maxSkew)
+	}
+	if !(c.precision > 0) || c.precision > c.maxSkew {
+		return 0, fmt.Errorf("deskew: invalid precision %g", c.precision)
+	}
+	if im == nil {
+		return 0, errors.New("deskew: nil image")
+	}
+
+	// Horizontal lines have a normal at Pi/2, search either side of it with
+	// several distance buckets per pixel.
+	angles := int(math.Ceil(2 * c.maxSkew / c.precision))
+	b := im.Bounds()
+	distances := perPixel * int(math.Ceil(math.Hypot(float64(b.Dx()), float64(b.Dy()))))
+	acc, err := hough.Transform(im,
+		hough.Size(distances, angles),
+		hough.AngleRange(math.Pi/2-c.maxSkew, math.Pi/2+c.maxSkew),
+		hough.Votes(func(r, g, b, a uint32) bool {
+			return (299*r+587*g+114*b)/1000 < c.threshold
+		}),
+		// The buckets are fine enough for each vote to go to the nearest,
+		// the columns are smoothed when they are scored.
+		hough.Spread(hough.Nearest),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// At the skew angle the votes of each text line collect in a few
+	// buckets rather than being spread out, which maximises the sum of
+	// squared votes in the column. Each column is first smoothed along the
+	// distance axis by a gaussian with a standard deviation of a pixel so
+	// that the score does not depend on where the lines fall between
+	// buckets.
+	var smooth [6*perPixel + 1]float64
+	var total float64
+	for i := range smooth {
+		o := float64(i-len(smooth)/2) / perPixel
+		smooth[i] = math.Exp(-o * o / 2)
+		total += smooth[i]
+	}
+	for i := range smooth {
+		smooth[i] /= total
+	}
+	scores := make([]float64, angles)
+	best := 0
+	for x := 0; x < angles; x++ {
+		for y := 0; y < distances; y++ {
+			var v float64
+			for i, w := range smooth {
+				v += w * float64(acc.Gray16At(x, y+i-len(smooth)/2).Y)
+			}
+			scores[x] += v * v
+		}
+		if scores[x] > scores[best] {
+			best = x
+		}
+	}
+	if scores[best] == 0 {
+		return 0, errors.New("deskew: no text found")
+	}
+	// Interpolate the peak by fitting a parabola through its neighbours.
+	peak := float64(best)
+	if best > 0 && best < angles-1 {
+		l, m, r := scores[best-1], scores[best], scores[best+1]
+		if d := l - 2*m + r; d != 0 {
+			peak += 0.5 * (l - r) / d
+		}
+	}
+	return acc.Angle(peak) - math.Pi/2, nil
+}
+
+// Deskew estimates the skew of im and returns its luminance rotated to
+// correct it, with the corners rotated in from outside im white, along with
+// the estimated skew angle.
+func Deskew(im image.Image, opts ...Option) (*gray16.Gray16, float64, error) {
+	angle, err := Angle(im, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+	upright, err := warp.Rotate(gray16.From(im, gray16.Rec601), -angle, warp.Crop, warp.Fill(math.MaxUint16))
+	if err != nil {
+		return nil, 0, err
+	}
+	return upright, angle, nil
+}
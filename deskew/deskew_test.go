@@ -0,0 +1,68 @@
+package deskew
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// page returns an image of dashed lines of text skewed by angle radians.
+func page(angle float64) *image.Gray {
+	im := image.NewGray(image.Rect(0, 0, 300, 240))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	slope := math.Tan(angle)
+	for line := 40; line < 200; line += 20 {
+		for x := 20; x < 280; x++ {
+			// Gaps between the words.
+			if x%30 > 24 {
+				continue
+			}
+			y := float64(line) + float64(x-150)*slope
+			for dy := 0; dy < 3; dy++ {
+				im.SetGray(x, int(y+0.5)+dy, color.Gray{})
+			}
+		}
+	}
+	return im
+}
+
+func TestAngle(t *testing.T) {
+	for _, degrees := range []float64{-4.5, -1, 0, 2.3, 7} {
+		expected := degrees * math.Pi / 180
+		angle, err := Angle(page(expected))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(angle-expected) > 0.1*math.Pi/180 {
+			t.Errorf("Expecting skew of %.2f degrees got %.2f", degrees, angle*180/math.Pi)
+		}
+	}
+}
+
+func TestDeskew(t *testing.T) {
+	skewed := page(3 * math.Pi / 180)
+	upright, angle, err := Deskew(skewed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(angle-3*math.Pi/180) > 0.1*math.Pi/180 {
+		t.Errorf("Expecting skew of 3 degrees got %.2f", angle*180/math.Pi)
+	}
+	residual, err := Angle(upright)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(residual) > 0.1*math.Pi/180 {
+		t.Errorf("Expecting no skew after deskewing got %.2f degrees", residual*180/math.Pi)
+	}
+}
+
+func TestAngleNoText(t *testing.T) {
+	im := image.NewGray(image.Rect(0, 0, 10, 10))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	if _, err := Angle(im); err == nil {
+		t.Error("Expecting error for a blank page got nil")
+	}
+}
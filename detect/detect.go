@@ -0,0 +1,81 @@
+// Package detect provides line detectors behind a common interface so that
+// they can be compared on the same data.
+package detect
+
+import (
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/blob"
+	"github.com/piersy/hough-go/conv"
+	"github.com/piersy/hough-go/hough"
+	"github.com/piersy/hough-go/point"
+)
+
+// Segment is a detected line segment. Detectors that find infinite lines
+// report the part of the line that crosses the image.
+type Segment struct {
+	From, To point.Point
+	// Score is the strength of the detection, its meaning depends on the
+	// detector and so scores are only comparable between segments from the
+	// same detector.
+	Score float64
+}
+
+// Detector detects line segments in an image.
+type Detector interface {
+	Detect(im image.Image) ([]Segment, error)
+}
+
+// Hough detects lines with the hough transform. The accumulator is butterfly
+// filtered and thresholded, the blobs remaining are taken as lines and
+// neighbouring lines are merged. The zero value uses a 400x400 accumulator
+// of black pixels and merges lines within 2 degrees and 5 pixels. Scores are
+// the votes of the lines.
+type Hough struct {
+	// Distances and Angles set the size of the accumulator.
+	Distances, Angles int
+	// MergeAngle, in radians, and MergeDistance, in pixels, set the
+	// tolerances within which lines are merged.
+	MergeAngle, MergeDistance float64
+	// Options are passed to the transform after the size.
+	Options []hough.Option
+}
+
+// Detect implements Detector.
+func (h Hough) Detect(im image.Image) ([]Segment, error) {
+	distances, angles := h.Distances, h.Angles
+	if distances == 0 {
+		distances = 400
+	}
+	if angles == 0 {
+		angles = 400
+	}
+	mergeAngle, mergeDistance := h.MergeAngle, h.MergeDistance
+	if mergeAngle == 0 {
+		mergeAngle = 2 * math.Pi / 180
+	}
+	if mergeDistance == 0 {
+		mergeDistance = 5
+	}
+	acc, err := hough.Transform(im, append([]hough.Option{hough.Size(distances, angles)}, h.Options...)...)
+	if err != nil {
+		return nil, err
+	}
+	filtered := hough.Butterfly(acc)
+	if filtered.MaxVal == 0 {
+		return nil, nil
+	}
+	filtered.Normalise()
+	var lines []hough.Line
+	for _, b := range blob.Find(conv.AdaptiveThresh(filtered.Gray16)) {
+		lines = append(lines, acc.LineAt(b.Centre()))
+	}
+	var segments []Segment
+	for _, l := range hough.Merge(lines, mergeAngle, mergeDistance) {
+		if from, to, ok := acc.Ends(l); ok {
+			segments = append(segments, Segment{From: from, To: to, Score: l.Votes})
+		}
+	}
+	return segments, nil
+}
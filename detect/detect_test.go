@@ -0,0 +1,81 @@
+package detect
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// lineImage returns a white image with a black line two pixels thick from
+// (20, 30) to (180, 110).
+func lineImage() image.Image {
+	im := image.NewRGBA(image.Rect(0, 0, 200, 150))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for i := 0; i <= 400; i++ {
+		f := float64(i) / 400
+		x := 20 + f*160
+		y := 30 + f*80
+		im.Set(int(x), int(y), color.Black)
+		im.Set(int(x), int(y)+1, color.Black)
+	}
+	return im
+}
+
+// near reports whether the segment lies along the line of lineImage.
+func near(s Segment) bool {
+	// Distance of a point from the line through (20, 30) and (180, 110).
+	dist := func(x, y float64) float64 {
+		return math.Abs(80*(x-20)-160*(y-30)) / math.Hypot(160, 80)
+	}
+	return dist(s.From.X, s.From.Y) < 3 && dist(s.To.X, s.To.Y) < 3 &&
+		math.Hypot(s.To.X-s.From.X, s.To.Y-s.From.Y) > 50
+}
+
+func TestDetectors(t *testing.T) {
+	im := lineImage()
+	for name, d := range map[string]Detector{
+		"hough":  Hough{},
+		"ransac": RANSAC{},
+		"lsd":    LSD{},
+	} {
+		segments, err := d.Detect(im)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		found := false
+		for _, s := range segments {
+			if near(s) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: no segment along the line in %+v", name, segments)
+		}
+	}
+}
+
+func TestDetectNil(t *testing.T) {
+	for name, d := range map[string]Detector{
+		"hough":  Hough{},
+		"ransac": RANSAC{},
+		"lsd":    LSD{},
+	} {
+		if _, err := d.Detect(nil); err == nil {
+			t.Errorf("%s: expecting an error for a nil image", name)
+		}
+	}
+}
+
+func TestLSDBlank(t *testing.T) {
+	im := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	segments, err := LSD{}.Detect(im)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 0 {
+		t.Errorf("found %d segments in a blank image", len(segments))
+	}
+}
@@ -0,0 +1,327 @@
+package detect
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/piersy/hough-go/edge"
+	"github.com/piersy/hough-go/point"
+)
+
+// LSD detects line segments with the Line Segment Detector of von Gioi et
+// al. Pixels are grouped into regions whose level-line angles, the
+// directions perpendicular to their intensity gradients, agree. Each region
+// is approximated by a rectangle which is accepted as a segment if the
+// number of pixels within it aligned with it would be unlikely to occur by
+// chance, the a contrario validation. The image is smoothed but not scaled
+// and the rectangle refinement of the reference implementation is not
+// performed. Segments are scored by -log10 of their number of false alarms.
+// The zero value uses the defaults given for each field.
+type LSD struct {
+	// AngleTolerance is the largest difference, in radians, between the
+	// level-line angle of a pixel and its region, the default is 22.5
+	// degrees.
+	AngleTolerance float64
+	// Epsilon is the -log10 number of false alarms a segment must exceed,
+	// the default of 0 accepts segments expected to occur by chance less
+	// than once per image.
+	Epsilon float64
+}
+
+// lsdSigma is the standard deviation of the Gaussian the image is smoothed
+// with to reduce the staircase effect of aliased edges, it is that used by
+// the reference implementation before scaling by 0.8.
+const lsdSigma = 0.6 / 0.8
+
+// notDefined marks the level-line angle of pixels without a meaningful
+// gradient.
+const notDefined = -1024.0
+
+// Detect implements Detector.
+func (l LSD) Detect(im image.Image) ([]Segment, error) {
+	if im == nil {
+		return nil, errors.New("detect: nil image")
+	}
+	tau := l.AngleTolerance
+	if tau == 0 {
+		tau = 22.5 * math.Pi / 180
+	}
+	p := tau / math.Pi
+
+	b := im.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 2 || h < 2 {
+		return nil, nil
+	}
+	at := edge.RGBA(im)
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := at(b.Min.X+x, b.Min.Y+y)
+			lum[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+		}
+	}
+
+	lum = smooth(lum, w, h, lsdSigma)
+
+	// Gradients are computed with a 2x2 mask so the gradient of pixel (x, y)
+	// lies at (x+0.5, y+0.5). Gradients that are small relative to the
+	// quantisation error of the pixel values, q, give unreliable angles.
+	const q = 2.0
+	threshold := q / math.Sin(tau)
+	angles := make([]float64, w*h)
+	mags := make([]float64, w*h)
+	var order []int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			angles[i] = notDefined
+			if x == w-1 || y == h-1 {
+				continue
+			}
+			a, bb, c, d := lum[i], lum[i+1], lum[i+w], lum[i+w+1]
+			gx := (bb + d - a - c) / 2
+			gy := (c + d - a - bb) / 2
+			mag := math.Hypot(gx, gy)
+			if mag <= threshold {
+				continue
+			}
+			angles[i] = math.Atan2(gx, -gy)
+			mags[i] = mag
+			order = append(order, i)
+		}
+	}
+	// Regions are grown from the pixels with the strongest gradients first.
+	sort.SliceStable(order, func(i, j int) bool {
+		return mags[order[i]] > mags[order[j]]
+	})
+
+	logNT := 5*(math.Log10(float64(w))+math.Log10(float64(h)))/2 + math.Log10(11)
+	minRegion := int(-logNT / math.Log10(p))
+	used := make([]bool, w*h)
+	var segments []Segment
+	for _, seed := range order {
+		if used[seed] {
+			continue
+		}
+		region, regionAngle := growRegion(seed, angles, used, w, h, tau)
+		if len(region) < minRegion {
+			continue
+		}
+		r := regionRect(region, mags, regionAngle, w)
+		nfa := r.nfa(angles, w, h, tau, p, logNT)
+		if nfa > l.Epsilon {
+			segments = append(segments, Segment{
+				From:  point.Point{X: r.x1 + float64(b.Min.X), Y: r.y1 + float64(b.Min.Y)},
+				To:    point.Point{X: r.x2 + float64(b.Min.X), Y: r.y2 + float64(b.Min.Y)},
+				Score: nfa,
+			})
+		}
+	}
+	return segments, nil
+}
+
+// smooth returns the w by h image v convolved with a Gaussian of standard
+// deviation sigma, with edges extended.
+func smooth(v []float64, w, h int, sigma float64) []float64 {
+	radius := int(math.Ceil(sigma * 3))
+	kernel := make([]float64, 2*radius+1)
+	var total float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+	clamp := func(i, n int) int {
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	}
+	tmp := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for i, k := range kernel {
+				sum += k * v[y*w+clamp(x+i-radius, w)]
+			}
+			tmp[y*w+x] = sum
+		}
+	}
+	out := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for i, k := range kernel {
+				sum += k * tmp[clamp(y+i-radius, h)*w+x]
+			}
+			out[y*w+x] = sum
+		}
+	}
+	return out
+}
+
+// angleDiff returns the absolute difference between two angles.
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 2*math.Pi)
+	if d > math.Pi {
+		d = 2*math.Pi - d
+	}
+	return d
+}
+
+// growRegion grows a region from seed by adding the 8-connected neighbours
+// whose level-line angle is within tau of the angle of the region, which is
+// updated as pixels are added. Pixels of the region are marked as used.
+func growRegion(seed int, angles []float64, used []bool, w, h int, tau float64) ([]int, float64) {
+	region := []int{seed}
+	used[seed] = true
+	regionAngle := angles[seed]
+	sumX, sumY := math.Cos(regionAngle), math.Sin(regionAngle)
+	for i := 0; i < len(region); i++ {
+		x, y := region[i]%w, region[i]/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				n := ny*w + nx
+				if used[n] || angles[n] == notDefined || angleDiff(angles[n], regionAngle) > tau {
+					continue
+				}
+				used[n] = true
+				region = append(region, n)
+				sumX += math.Cos(angles[n])
+				sumY += math.Sin(angles[n])
+				regionAngle = math.Atan2(sumY, sumX)
+			}
+		}
+	}
+	return region, regionAngle
+}
+
+// rect is the rectangle approximating a region, it runs from (x1, y1) to
+// (x2, y2) along its centre line in the direction theta and spans width.
+type rect struct {
+	x1, y1, x2, y2 float64
+	cx, cy         float64
+	theta          float64
+	lmin, lmax     float64
+	wmin, wmax     float64
+}
+
+// regionRect returns the rectangle approximating the region, centred on
+// its gradient magnitude weighted centroid and aligned with its principal
+// axis.
+func regionRect(region []int, mags []float64, regionAngle float64, w int) rect {
+	var r rect
+	var total float64
+	for _, i := range region {
+		r.cx += mags[i] * (float64(i%w) + 0.5)
+		r.cy += mags[i] * (float64(i/w) + 0.5)
+		total += mags[i]
+	}
+	r.cx /= total
+	r.cy /= total
+	var sxx, syy, sxy float64
+	for _, i := range region {
+		dx, dy := float64(i%w)+0.5-r.cx, float64(i/w)+0.5-r.cy
+		sxx += mags[i] * dx * dx
+		syy += mags[i] * dy * dy
+		sxy += mags[i] * dx * dy
+	}
+	r.theta = 0.5 * math.Atan2(2*sxy, sxx-syy)
+	// Orient the rectangle like the level-lines of the region.
+	if angleDiff(r.theta, regionAngle) > math.Pi/2 {
+		r.theta += math.Pi
+	}
+	dx, dy := math.Cos(r.theta), math.Sin(r.theta)
+	r.lmin, r.lmax = math.Inf(1), math.Inf(-1)
+	r.wmin, r.wmax = math.Inf(1), math.Inf(-1)
+	for _, i := range region {
+		px, py := float64(i%w)+0.5-r.cx, float64(i/w)+0.5-r.cy
+		l := px*dx + py*dy
+		wd := -px*dy + py*dx
+		r.lmin, r.lmax = math.Min(r.lmin, l), math.Max(r.lmax, l)
+		r.wmin, r.wmax = math.Min(r.wmin, wd), math.Max(r.wmax, wd)
+	}
+	// The rectangle is at least a pixel wide.
+	if r.wmax-r.wmin < 1 {
+		mid := (r.wmax + r.wmin) / 2
+		r.wmin, r.wmax = mid-0.5, mid+0.5
+	}
+	r.x1, r.y1 = r.cx+r.lmin*dx, r.cy+r.lmin*dy
+	r.x2, r.y2 = r.cx+r.lmax*dx, r.cy+r.lmax*dy
+	return r
+}
+
+// nfa returns -log10 of the number of false alarms of the rectangle, from
+// the number of pixels within it whose level-line angle is aligned with it
+// to within tau, each of which occurs with probability p by chance.
+func (r rect) nfa(angles []float64, w, h int, tau, p, logNT float64) float64 {
+	dx, dy := math.Cos(r.theta), math.Sin(r.theta)
+	// Scan the bounding box of the rectangle.
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, l := range []float64{r.lmin, r.lmax} {
+		for _, wd := range []float64{r.wmin, r.wmax} {
+			x := r.cx + l*dx - wd*dy
+			y := r.cy + l*dy + wd*dx
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+	n, k := 0, 0
+	for y := int(math.Max(0, math.Floor(minY))); y <= int(math.Min(float64(h-1), maxY)); y++ {
+		for x := int(math.Max(0, math.Floor(minX))); x <= int(math.Min(float64(w-1), maxX)); x++ {
+			px, py := float64(x)+0.5-r.cx, float64(y)+0.5-r.cy
+			l := px*dx + py*dy
+			wd := -px*dy + py*dx
+			if l < r.lmin || l > r.lmax || wd < r.wmin || wd > r.wmax {
+				continue
+			}
+			n++
+			a := angles[y*w+x]
+			if a != notDefined && angleDiff(a, r.theta) <= tau {
+				k++
+			}
+		}
+	}
+	return -logNT - logBinomialTail(n, k, p)
+}
+
+// logBinomialTail returns log10 of the probability of at least k successes
+// in n trials each with probability p.
+func logBinomialTail(n, k int, p float64) float64 {
+	if k > n {
+		return math.Inf(-1)
+	}
+	if k <= 0 {
+		return 0
+	}
+	ln := func(v int) float64 {
+		l, _ := math.Lgamma(float64(v) + 1)
+		return l
+	}
+	// The first term of the tail in log space, later terms are computed
+	// relative to it.
+	first := ln(n) - ln(k) - ln(n-k) + float64(k)*math.Log(p) + float64(n-k)*math.Log(1-p)
+	sum, term := 1.0, 1.0
+	for i := k + 1; i <= n; i++ {
+		term *= float64(n-i+1) / float64(i) * p / (1 - p)
+		sum += term
+		if float64(i) > float64(n)*p && term < sum*1e-12 {
+			break
+		}
+	}
+	return math.Min(0, (first+math.Log(sum))/math.Ln10)
+}
@@ -0,0 +1,115 @@
+package detect
+
+import (
+	"errors"
+	"image"
+	"math"
+	"math/rand"
+
+	"github.com/piersy/hough-go/edge"
+	"github.com/piersy/hough-go/point"
+)
+
+// RANSAC detects lines by sequential RANSAC. The line supported by the most
+// foreground pixels is found by random sampling of pairs of pixels, its
+// supporting pixels are removed and the search repeated until no line has
+// enough support. Segments span the supporting pixels and are scored by
+// their number. The zero value uses the defaults given for each field.
+type RANSAC struct {
+	// Select selects the foreground pixels, the default is edge.Black.
+	Select edge.Predicate
+	// Threshold is the distance in pixels within which a pixel supports a
+	// line, the default is 1.
+	Threshold float64
+	// MinSupport is the least number of pixels that must support a line,
+	// the default is 30.
+	MinSupport int
+	// MaxLines limits the number of lines found, the default is 10.
+	MaxLines int
+	// Iterations is the number of pairs sampled for each line, the default
+	// is 500.
+	Iterations int
+	// Seed seeds the random sampling so that results are repeatable.
+	Seed int64
+}
+
+// Detect implements Detector.
+func (r RANSAC) Detect(im image.Image) ([]Segment, error) {
+	if im == nil {
+		return nil, errors.New("detect: nil image")
+	}
+	sel := r.Select
+	if sel == nil {
+		sel = edge.Black
+	}
+	threshold := r.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	minSupport := r.MinSupport
+	if minSupport == 0 {
+		minSupport = 30
+	}
+	maxLines := r.MaxLines
+	if maxLines == 0 {
+		maxLines = 10
+	}
+	iterations := r.Iterations
+	if iterations == 0 {
+		iterations = 500
+	}
+	rnd := rand.New(rand.NewSource(r.Seed))
+
+	pts := edge.Extract(im, edge.Select(sel)).Points
+	var segments []Segment
+	for len(segments) < maxLines && len(pts) >= minSupport {
+		// The best line is held as a point on it and its unit normal.
+		var best int
+		var bp point.Point
+		var bnx, bny float64
+		for i := 0; i < iterations; i++ {
+			p, q := pts[rnd.Intn(len(pts))], pts[rnd.Intn(len(pts))]
+			dx, dy := float64(q.X-p.X), float64(q.Y-p.Y)
+			length := math.Hypot(dx, dy)
+			if length == 0 {
+				continue
+			}
+			nx, ny := -dy/length, dx/length
+			support := 0
+			for _, s := range pts {
+				if math.Abs(float64(s.X-p.X)*nx+float64(s.Y-p.Y)*ny) <= threshold {
+					support++
+				}
+			}
+			if support > best {
+				best = support
+				bp = point.Point{X: float64(p.X), Y: float64(p.Y)}
+				bnx, bny = nx, ny
+			}
+		}
+		if best < minSupport {
+			break
+		}
+		// Remove the support of the line, keeping the extent of its
+		// projection onto the line.
+		lo, hi := math.Inf(1), math.Inf(-1)
+		remaining := pts[:0]
+		for _, s := range pts {
+			x, y := float64(s.X)-bp.X, float64(s.Y)-bp.Y
+			if math.Abs(x*bnx+y*bny) > threshold {
+				remaining = append(remaining, s)
+				continue
+			}
+			along := -x*bny + y*bnx
+			lo = math.Min(lo, along)
+			hi = math.Max(hi, along)
+		}
+		pts = remaining
+		segments = append(segments, Segment{
+			From:  point.Point{X: bp.X - lo*bny, Y: bp.Y + lo*bnx},
+			To:    point.Point{X: bp.X - hi*bny, Y: bp.Y + hi*bnx},
+			Score: float64(best),
+		})
+	}
+	return segments, nil
+}
@@ -0,0 +1,187 @@
+// Package edge extracts the foreground pixels of an image, such as the edges
+// of an edge map, into a compact list of coordinates. Edge maps are usually
+// mostly empty so consumers of the list, such as the hough transforms, avoid
+// visiting every pixel of the image.
+package edge
+
+import (
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// Predicate reports whether a pixel is part of the foreground. It is given
+// the alpha-premultiplied colour components of the pixel as returned by
+// color.Color.RGBA.
+type Predicate func(r, g, b, a uint32) bool
+
+// Weigher returns the weight of a foreground pixel, it is given the same
+// colour components as a Predicate.
+type Weigher func(r, g, b, a uint32) float64
+
+// Black is a Predicate that selects pixels that are black.
+func Black(r, g, b, a uint32) bool {
+	return r&g&b == 0
+}
+
+// Constant returns a Weigher that gives every pixel the weight v.
+func Constant(v float64) Weigher {
+	return func(r, g, b, a uint32) float64 {
+		return v
+	}
+}
+
+// Point is a foreground pixel.
+type Point struct {
+	X, Y int
+	// Weight is the weight of the pixel.
+	Weight float64
+	// Gradient is the direction, in radians, of the intensity gradient at
+	// the pixel, it points from dark to light. It is NaN if gradients were
+	// not extracted or the pixel lies in a region of constant intensity.
+	Gradient float64
+}
+
+// List is the foreground pixels of an image in row major order.
+type List struct {
+	Points []Point
+	// Bounds is the bounds of the image the points were extracted from.
+	Bounds image.Rectangle
+}
+
+// Option configures Extract.
+type Option func(*config)
+
+type config struct {
+	predicate Predicate
+	weigher   Weigher
+	gradients bool
+}
+
+// Select sets the predicate that selects the foreground pixels. The default
+// is Black.
+func Select(p Predicate) Option {
+	return func(c *config) {
+		c.predicate = p
+	}
+}
+
+// Weigh sets the function that weights the foreground pixels. The default
+// gives every pixel a weight of 1.
+func Weigh(w Weigher) Option {
+	return func(c *config) {
+		c.weigher = w
+	}
+}
+
+// Gradients enables the extraction of the gradient direction of each
+// foreground pixel, estimated from the luminance of the image with a Sobel
+// operator.
+func Gradients() Option {
+	return func(c *config) {
+		c.gradients = true
+	}
+}
+
+// Extract returns the foreground pixels of im.
+func Extract(im image.Image, opts ...Option) *List {
+	c := config{
+		predicate: Black,
+		weigher:   Constant(1),
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	b := im.Bounds()
+	at := RGBA(im)
+	l := &List{Bounds: b}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := at(x, y)
+			if !c.predicate(r, g, bl, a) {
+				continue
+			}
+			l.Points = append(l.Points, Point{X: x, Y: y, Weight: c.weigher(r, g, bl, a), Gradient: math.NaN()})
+		}
+	}
+	if c.gradients {
+		for i, p := range l.Points {
+			l.Points[i].Gradient = gradient(at, b, p.X, p.Y)
+		}
+	}
+	return l
+}
+
+// sobelX is the horizontal Sobel operator, the vertical operator is its
+// transpose.
+var sobelX = [3][3]float64{
+	{-1, 0, 1},
+	{-2, 0, 2},
+	{-1, 0, 1},
+}
+
+// gradient returns the direction of the luminance gradient at (x, y), pixels
+// beyond the bounds b are replaced by the nearest pixel within them.
+func gradient(at func(int, int) (uint32, uint32, uint32, uint32), b image.Rectangle, x, y int) float64 {
+	var gx, gy float64
+	for j := -1; j <= 1; j++ {
+		for i := -1; i <= 1; i++ {
+			px := clamp(x+i, b.Min.X, b.Max.X-1)
+			py := clamp(y+j, b.Min.Y, b.Max.Y-1)
+			r, g, bl, _ := at(px, py)
+			l := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			gx += sobelX[j+1][i+1] * l
+			gy += sobelX[i+1][j+1] * l
+		}
+	}
+	if gx == 0 && gy == 0 {
+		return math.NaN()
+	}
+	return math.Atan2(gy, gx)
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// RGBA returns the At method defined on the underlying struct implementing
+// image.Image, if the image type is unknown then the generic At method of the
+// interface is used.
+// This performs about 25% better than calling at on an interface, best
+// performance is achieved by calling the types at method in the main loop but
+// that would mean writing the loop once for each image type.
+func RGBA(i image.Image) func(int, int) (uint32, uint32, uint32, uint32) {
+	switch t := i.(type) {
+	case *image.Gray:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return t.GrayAt(x, y).RGBA()
+		}
+	case *image.Gray16:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return t.Gray16At(x, y).RGBA()
+		}
+	case *gray16.Gray16:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return t.Gray16At(x, y).RGBA()
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return t.NRGBAAt(x, y).RGBA()
+		}
+	case *image.RGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return t.RGBAAt(x, y).RGBA()
+		}
+	default:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return i.At(x, y).RGBA()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package edge
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	// White on the left, black on the right.
+	im := image.NewGray(image.Rect(10, 20, 20, 30))
+	draw.Draw(im, image.Rect(10, 20, 15, 30), image.NewUniform(color.White), image.ZP, draw.Src)
+
+	l := Extract(im, Weigh(Constant(3)), Gradients())
+	if l.Bounds != im.Bounds() {
+		t.Errorf("Expecting bounds %v got %v", im.Bounds(), l.Bounds)
+	}
+	if len(l.Points) != 50 {
+		t.Fatalf("Expecting 50 points got %d", len(l.Points))
+	}
+	for _, p := range l.Points {
+		if p.X < 15 || p.Weight != 3 {
+			t.Fatalf("Unexpected point %+v", p)
+		}
+		switch {
+		case p.X == 15 && p.Gradient != math.Pi:
+			// The gradient points from dark to light.
+			t.Errorf("Expecting gradient of Pi at %+v", p)
+		case p.X > 15 && !math.IsNaN(p.Gradient):
+			t.Errorf("Expecting no gradient at %+v", p)
+		}
+	}
+	if p := l.Points[0]; p.X != 15 || p.Y != 20 {
+		t.Errorf("Expecting points in row major order, got %+v first", p)
+	}
+}
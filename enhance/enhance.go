@@ -0,0 +1,263 @@
+// Package enhance improves the contrast of gray16 images by histogram
+// equalisation, working at their full 16 bit depth.
+package enhance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// levels is the number of values a gray16 pixel can take.
+const levels = math.MaxUint16 + 1
+
+// Equalise returns a copy of g with its histogram equalised, each value is
+// mapped to the fraction of pixels at or below it so that the values of the
+// result are spread evenly over the full range. The lowest value of g maps to
+// 0 and the highest to math.MaxUint16. An image whose pixels all have the same
+// value is copied unchanged.
+func Equalise(g *gray16.Gray16) (*gray16.Gray16, error) {
+	if g == nil {
+		return nil, errors.New("enhance: nil image")
+	}
+	out := gray16.NewGray16(g.Rect)
+	b := g.Rect
+	w := b.Dx()
+	counts := make([]int, levels)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := g.PixOffset(b.Min.X, y)
+		for _, v := range g.Pix[i : i+w] {
+			counts[v]++
+		}
+	}
+	min, max := g.Range()
+	mapping := make([]uint16, levels)
+	if min == max {
+		mapping[min] = min
+	} else {
+		// The pixels of the lowest value are excluded so that it maps to 0.
+		total := w*b.Dy() - counts[min]
+		cumulative := 0
+		for v := int(min) + 1; v <= int(max); v++ {
+			cumulative += counts[v]
+			mapping[v] = uint16((uint64(cumulative)*math.MaxUint16 + uint64(total)/2) / uint64(total))
+		}
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i, o := g.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+		for x, v := range g.Pix[i : i+w] {
+			out.Pix[o+x] = mapping[v]
+		}
+	}
+	out.UpdateRange()
+	return out, nil
+}
+
+// Option configures CLAHE.
+type Option func(*config)
+
+type config struct {
+	tilesX, tilesY int
+	clipLimit      float64
+	bins           int
+}
+
+// Tiles sets the number of tiles across and down the image, the default is
+// 8 by 8. Smaller tiles adapt to more local contrast.
+func Tiles(x, y int) Option {
+	return func(c *config) {
+		c.tilesX, c.tilesY = x, y
+	}
+}
+
+// ClipLimit limits the contrast enhancement. Each bin of the histogram of a
+// tile is clipped to limit times the count it would have if the values of the
+// tile were spread uniformly over the bins, and the excess spread over all
+// bins. Tiles whose values fall in few bins are therefore limited more. A
+// limit of 1 stretches the range of the image linearly over the full range
+// and larger limits enhance contrast more, amplifying noise in flat regions.
+// The default is 2.
+func ClipLimit(limit float64) Option {
+	return func(c *config) {
+		c.clipLimit = limit
+	}
+}
+
+// Bins sets the number of bins of the histogram of each tile, from 2 to
+// 65536, the default is 4096. The bins span the range of values of the image,
+// an image with fewer distinct values in its range than bins has a bin for
+// each value. Values within a bin are mapped by linear interpolation so the
+// result keeps the full 16 bit depth whatever the number of bins.
+func Bins(n int) Option {
+	return func(c *config) {
+		c.bins = n
+	}
+}
+
+func (c *config) validate(g *gray16.Gray16) error {
+	if c.tilesX < 1 || c.tilesY < 1 {
+		return fmt.Errorf("enhance: invalid tile grid %dx%d", c.tilesX, c.tilesY)
+	}
+	if c.tilesX > g.Rect.Dx() || c.tilesY > g.Rect.Dy() {
+		return fmt.Errorf("enhance: tile grid %dx%d is finer than the %dx%d image", c.tilesX, c.tilesY, g.Rect.Dx(), g.Rect.Dy())
+	}
+	if !(c.clipLimit >= 1) || math.IsInf(c.clipLimit, 1) {
+		return fmt.Errorf("enhance: invalid clip limit %g, it must be at least 1", c.clipLimit)
+	}
+	if c.bins < 2 || c.bins > levels {
+		return fmt.Errorf("enhance: invalid bin count %d", c.bins)
+	}
+	return nil
+}
+
+// CLAHE returns a copy of g enhanced by contrast limited adaptive histogram
+// equalisation. The image is divided into a grid of tiles and the clipped
+// histogram of each tile equalised. Each pixel is mapped by bilinear
+// interpolation between the mappings of the four tiles whose centres
+// surround it, so that there are no seams between tiles. As with Equalise, the
+// result spans the full range however narrow the range of g, and an image
+// whose pixels all have the same value is copied unchanged.
+func CLAHE(g *gray16.Gray16, opts ...Option) (*gray16.Gray16, error) {
+	if g == nil {
+		return nil, errors.New("enhance: nil image")
+	}
+	c := config{tilesX: 8, tilesY: 8, clipLimit: 2, bins: 4096}
+	for _, o := range opts {
+		o(&c)
+	}
+	if err := c.validate(g); err != nil {
+		return nil, err
+	}
+	b := g.Rect
+	min, max := g.Range()
+	if min == max {
+		out := gray16.NewGray16(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i, o := g.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+			copy(out.Pix[o:o+b.Dx()], g.Pix[i:i+b.Dx()])
+		}
+		out.UpdateRange()
+		return out, nil
+	}
+	// The edges of the tiles, relative to the image.
+	xs := edges(b.Dx(), c.tilesX)
+	ys := edges(b.Dy(), c.tilesY)
+	// The bins span the range of the image, min to max inclusive.
+	span := int(max) - int(min) + 1
+	if span < c.bins {
+		c.bins = span
+	}
+	binWidth := float64(span) / float64(c.bins)
+	binOf := func(v uint16) int {
+		bin := int(float64(int(v)-int(min)) / binWidth)
+		if bin >= c.bins {
+			bin = c.bins - 1
+		}
+		return bin
+	}
+
+	// The cumulative distribution of each tile, mappings[t][bin] is the
+	// fraction of the pixels of tile t in bins up to and including bin.
+	mappings := make([][]float64, c.tilesX*c.tilesY)
+	counts := make([]int, c.bins)
+	for ty := 0; ty < c.tilesY; ty++ {
+		for tx := 0; tx < c.tilesX; tx++ {
+			for i := range counts {
+				counts[i] = 0
+			}
+			for y := ys[ty]; y < ys[ty+1]; y++ {
+				row := g.PixOffset(b.Min.X, b.Min.Y+y)
+				for _, v := range g.Pix[row+xs[tx] : row+xs[tx+1]] {
+					counts[binOf(v)]++
+				}
+			}
+			pixels := (xs[tx+1] - xs[tx]) * (ys[ty+1] - ys[ty])
+			mappings[ty*c.tilesX+tx] = clippedCDF(counts, pixels, c.clipLimit)
+		}
+	}
+
+	// at returns the mapping of v by tile (tx, ty), interpolated within the
+	// bin of v.
+	at := func(tx, ty int, v uint16) float64 {
+		m := mappings[ty*c.tilesX+tx]
+		bin := binOf(v)
+		lo := 0.0
+		if bin > 0 {
+			lo = m[bin-1]
+		}
+		f := (float64(int(v)-int(min)) + 1 - float64(bin)*binWidth) / binWidth
+		if f > 1 {
+			f = 1
+		}
+		return lo + f*(m[bin]-lo)
+	}
+	// locate returns the tiles either side of the pixel p along an axis and
+	// the weight of the second, from the tile centres.
+	locate := func(p int, edges []int) (t0, t1 int, f float64) {
+		n := len(edges) - 1
+		centre := func(t int) float64 { return float64(edges[t]+edges[t+1]) / 2 }
+		pos := float64(p) + 0.5
+		if pos <= centre(0) {
+			return 0, 0, 0
+		}
+		if pos >= centre(n-1) {
+			return n - 1, n - 1, 0
+		}
+		t0 = 0
+		for centre(t0+1) < pos {
+			t0++
+		}
+		return t0, t0 + 1, (pos - centre(t0)) / (centre(t0+1) - centre(t0))
+	}
+
+	out := gray16.NewGray16(b)
+	for y := 0; y < b.Dy(); y++ {
+		ty0, ty1, fy := locate(y, ys)
+		row, outRow := g.PixOffset(b.Min.X, b.Min.Y+y), out.PixOffset(b.Min.X, b.Min.Y+y)
+		for x := 0; x < b.Dx(); x++ {
+			tx0, tx1, fx := locate(x, xs)
+			v := g.Pix[row+x]
+			top := at(tx0, ty0, v)*(1-fx) + at(tx1, ty0, v)*fx
+			bottom := at(tx0, ty1, v)*(1-fx) + at(tx1, ty1, v)*fx
+			out.Pix[outRow+x] = uint16(math.Floor((top*(1-fy)+bottom*fy)*math.MaxUint16 + 0.5))
+		}
+	}
+	out.UpdateRange()
+	return out, nil
+}
+
+// edges returns the n+1 edges dividing size pixels into n tiles.
+func edges(size, n int) []int {
+	e := make([]int, n+1)
+	for i := range e {
+		e[i] = i * size / n
+	}
+	return e
+}
+
+// clippedCDF clips the counts of the histogram of a tile of the given number
+// of pixels, spreads the excess evenly over the bins and returns the
+// cumulative distribution.
+func clippedCDF(counts []int, pixels int, limit float64) []float64 {
+	n := len(counts)
+	clip := limit * float64(pixels) / float64(n)
+	clipped := make([]float64, n)
+	var excess float64
+	for i, c := range counts {
+		clipped[i] = float64(c)
+		if clipped[i] > clip {
+			excess += clipped[i] - clip
+			clipped[i] = clip
+		}
+	}
+	share := excess / float64(n)
+	cdf := make([]float64, n)
+	var sum float64
+	for i, c := range clipped {
+		sum += c + share
+		cdf[i] = sum / float64(pixels)
+	}
+	return cdf
+}
@@ -0,0 +1,150 @@
+package enhance
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+func TestEqualise(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(0, 0, 4, 1))
+	copy(g.Pix, []uint16{1000, 1001, 1001, 1003})
+	out, err := Equalise(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint16{0, 43690, 43690, math.MaxUint16}
+	for i, e := range expected {
+		if out.Pix[i] != e {
+			t.Errorf("Expecting %d at %d got %d", e, i, out.Pix[i])
+		}
+	}
+	if out.MinVal != 0 || out.MaxVal != math.MaxUint16 {
+		t.Errorf("Expecting the full range got %d to %d", out.MinVal, out.MaxVal)
+	}
+
+	c := gray16.NewGray16(image.Rect(0, 0, 2, 2))
+	for i := range c.Pix {
+		c.Pix[i] = 300
+	}
+	out, err = Equalise(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range out.Pix {
+		if v != 300 {
+			t.Errorf("Expecting a constant image to be unchanged got %d at %d", v, i)
+		}
+	}
+	if _, err := Equalise(nil); err == nil {
+		t.Error("Expecting an error for a nil image")
+	}
+}
+
+// TestNarrowRange enhances, with the default options, an image whose values
+// ramp over a narrow range and expects the result to span nearly the full
+// range.
+func TestNarrowRange(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			g.Pix[g.PixOffset(x, y)] = uint16(1000 + (x+y)*300/126)
+		}
+	}
+	g.UpdateRange()
+	equalised, err := Equalise(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clahe, err := CLAHE(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]*gray16.Gray16{"Equalise": equalised, "CLAHE": clahe} {
+		if out.MinVal > math.MaxUint16/20 || out.MaxVal < math.MaxUint16-math.MaxUint16/20 {
+			t.Errorf("%s: expecting nearly the full range got %d to %d", name, out.MinVal, out.MaxVal)
+		}
+	}
+}
+
+// halves returns an image whose left half ramps over a narrow range of dark
+// values and whose right half over a narrow range of light values. The values
+// rise with the square of the distance along each half, so that most pixels
+// are at the dark end of the ramp.
+func halves() *gray16.Gray16 {
+	g := gray16.NewGray16(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			v := 1000 + (x%32)*(x%32)*310/961
+			if x >= 32 {
+				v += 50000
+			}
+			g.Pix[g.PixOffset(x, y)] = uint16(v)
+		}
+	}
+	return g
+}
+
+func TestCLAHE(t *testing.T) {
+	g := halves()
+	out, err := CLAHE(g, Tiles(2, 1), ClipLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each half is stretched well beyond its original range of 310. The
+	// pixels up to the centre of the left tile and from the centre of the
+	// right tile are mapped by their own tile alone.
+	for _, x := range [][2]int{{0, 15}, {48, 63}} {
+		lo, hi := out.Gray16At(x[0], 16).Y, out.Gray16At(x[1], 16).Y
+		if int(hi)-int(lo) < 25000 {
+			t.Errorf("Expecting the half from %d to be stretched got %d to %d", x[0], lo, hi)
+		}
+	}
+	// The mapping is monotonic within a tile.
+	for x := 1; x < 16; x++ {
+		if out.Gray16At(x, 0).Y < out.Gray16At(x-1, 0).Y {
+			t.Errorf("Expecting increasing values along the ramp at %d", x)
+		}
+	}
+
+	// Equalisation spreads the crowded dark end of the ramp, a lower clip
+	// limit spreads it less and keeps the result closer to a linear stretch
+	// of the range of the image, 1000 to 51310.
+	limited, err := CLAHE(g, Tiles(2, 1), ClipLimit(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat, err := CLAHE(g, Tiles(2, 1), ClipLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := g.Gray16At(8, 0).Y
+	linear := (float64(v) - 1000 + 1) / 50311 * math.MaxUint16
+	e, l, f := out.Gray16At(8, 0).Y, limited.Gray16At(8, 0).Y, flat.Gray16At(8, 0).Y
+	if !(e > l && l > f) {
+		t.Errorf("Expecting %d to be spread less with lower limits got %d, %d and %d", v, e, l, f)
+	}
+	if math.Abs(float64(f)-linear) > math.Abs(float64(l)-linear) {
+		t.Errorf("Expecting a clip limit of 1 to be closest to the linear stretch %.0f got %d", linear, f)
+	}
+}
+func TestCLAHEValidation(t *testing.T) {
+	g := halves()
+	for name, opts := range map[string][]Option{
+		"tiles":      {Tiles(0, 4)},
+		"fine tiles": {Tiles(65, 1)},
+		"clip":       {ClipLimit(0.5)},
+		"nan clip":   {ClipLimit(math.NaN())},
+		"bins":       {Bins(1)},
+		"many bins":  {Bins(levels + 1)},
+	} {
+		if _, err := CLAHE(g, opts...); err == nil {
+			t.Errorf("%s: expecting an error", name)
+		}
+	}
+	if _, err := CLAHE(nil); err == nil {
+		t.Error("Expecting an error for a nil image")
+	}
+}
@@ -0,0 +1,133 @@
+package gray16
+
+import (
+	"image"
+	"image/color"
+)
+
+// Luminance selects how the red, green and blue channels of a colour are
+// combined into a gray value.
+type Luminance int
+
+const (
+	// Rec601 weighs the channels by ITU-R BT.601, as color.Gray16Model does.
+	Rec601 Luminance = iota
+	// Rec709 weighs the channels by ITU-R BT.709, as used for HD video and
+	// sRGB.
+	Rec709
+	// Red, Green and Blue take a single channel.
+	Red
+	Green
+	Blue
+	// MaxChannel takes the brightest channel, so that a saturated colour is as
+	// bright as white.
+	MaxChannel
+)
+
+// gray returns the gray value of the alpha premultiplied 16 bit channels.
+func (l Luminance) gray(r, g, b uint32) uint16 {
+	switch l {
+	case Rec709:
+		// 0.2126, 0.7152 and 0.0722 scaled to sum to 1<<16.
+		return uint16((13933*r + 46871*g + 4732*b + 1<<15) >> 16)
+	case Red:
+		return uint16(r)
+	case Green:
+		return uint16(g)
+	case Blue:
+		return uint16(b)
+	case MaxChannel:
+		if g > r {
+			r = g
+		}
+		if b > r {
+			r = b
+		}
+		return uint16(r)
+	}
+	// The same as color.Gray16Model.
+	return uint16((19595*r + 38470*g + 7471*b + 1<<15) >> 16)
+}
+
+// From returns img converted to a Gray16 of the same bounds, combining the
+// channels of each pixel by l. Colours are alpha premultiplied, as by
+// color.Color.RGBA, so transparent pixels become black. The common image
+// types of the standard library are converted without going through At.
+func From(img image.Image, l Luminance) *Gray16 {
+	b := img.Bounds()
+	out := NewGray16(b)
+	w := b.Dx()
+	switch src := img.(type) {
+	case *Gray16:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := src.PixOffset(b.Min.X, y)
+			copy(out.Pix[out.PixOffset(b.Min.X, y):], src.Pix[i:i+w])
+		}
+	case *image.Gray16:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i, o := src.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+			for x := 0; x < w; x++ {
+				out.Pix[o+x] = uint16(src.Pix[i+2*x])<<8 | uint16(src.Pix[i+2*x+1])
+			}
+		}
+	case *image.Gray:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i, o := src.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+			for x, v := range src.Pix[i : i+w] {
+				out.Pix[o+x] = uint16(v) * 0x101
+			}
+		}
+	case *image.RGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i, o := src.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+			for x := 0; x < w; x++ {
+				s := src.Pix[i+4*x : i+4*x+3]
+				out.Pix[o+x] = l.gray(uint32(s[0])*0x101, uint32(s[1])*0x101, uint32(s[2])*0x101)
+			}
+		}
+	case *image.NRGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i, o := src.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+			for x := 0; x < w; x++ {
+				s := src.Pix[i+4*x : i+4*x+4]
+				a := uint32(s[3])
+				out.Pix[o+x] = l.gray(uint32(s[0])*0x101*a/0xff, uint32(s[1])*0x101*a/0xff, uint32(s[2])*0x101*a/0xff)
+			}
+		}
+	case *image.YCbCr:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			o := out.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x++ {
+				yi, ci := src.YOffset(x, y), src.COffset(x, y)
+				r, g, bl, _ := color.YCbCr{Y: src.Y[yi], Cb: src.Cb[ci], Cr: src.Cr[ci]}.RGBA()
+				out.Pix[o+x-b.Min.X] = l.gray(r, g, bl)
+			}
+		}
+	case *image.Paletted:
+		// Convert each colour of the palette once.
+		lut := make([]uint16, 256)
+		for i, c := range src.Palette {
+			if i == len(lut) {
+				break
+			}
+			r, g, bl, _ := c.RGBA()
+			lut[i] = l.gray(r, g, bl)
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i, o := src.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+			for x, v := range src.Pix[i : i+w] {
+				out.Pix[o+x] = lut[v]
+			}
+		}
+	default:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			o := out.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, _ := img.At(x, y).RGBA()
+				out.Pix[o+x-b.Min.X] = l.gray(r, g, bl)
+			}
+		}
+	}
+	out.UpdateRange()
+	return out
+}
@@ -0,0 +1,78 @@
+package gray16
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+// opaque hides the type of an image so that From takes the generic path.
+type opaque struct {
+	image.Image
+}
+
+func TestFrom(t *testing.T) {
+	r := image.Rect(-3, 5, 14, 16)
+	rgba := image.NewRGBA(r)
+	nrgba := image.NewNRGBA(r)
+	gray := image.NewGray(r)
+	g16 := image.NewGray16(r)
+	own := NewGray16(r)
+	paletted := image.NewPaletted(r, palette.Plan9)
+	ycbcr := image.NewYCbCr(r, image.YCbCrSubsampleRatio420)
+	rnd := rand.New(rand.NewSource(1))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256))}
+			rgba.Set(x, y, c)
+			nrgba.Set(x, y, c)
+			gray.Set(x, y, c)
+			g16.Set(x, y, c)
+			own.Set(x, y, c)
+			paletted.Set(x, y, c)
+			ycbcr.Y[ycbcr.YOffset(x, y)] = c.R
+			ycbcr.Cb[ycbcr.COffset(x, y)] = c.G
+			ycbcr.Cr[ycbcr.COffset(x, y)] = c.B
+		}
+	}
+	images := map[string]image.Image{
+		"rgba": rgba, "nrgba": nrgba, "gray": gray, "gray16": g16,
+		"own": own, "paletted": paletted, "ycbcr": ycbcr,
+	}
+	for name, img := range images {
+		for l := Rec601; l <= MaxChannel; l++ {
+			fast, slow := From(img, l), From(opaque{img}, l)
+			if fast.Rect != r {
+				t.Fatalf("%s: expecting bounds %v got %v", name, r, fast.Rect)
+			}
+			for i := range slow.Pix {
+				if fast.Pix[i] != slow.Pix[i] {
+					t.Fatalf("%s %d: expecting %d at %d got %d", name, l, slow.Pix[i], i, fast.Pix[i])
+				}
+			}
+			if fast.MinVal != slow.MinVal || fast.MaxVal != slow.MaxVal {
+				t.Errorf("%s %d: expecting the range to be tracked", name, l)
+			}
+		}
+	}
+
+	// Rec601 agrees with the standard library.
+	g := From(opaque{nrgba}, Rec601)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if e := color.Gray16Model.Convert(nrgba.At(x, y)).(color.Gray16); g.Gray16At(x, y) != e {
+				t.Fatalf("Expecting %d at (%d, %d) got %d", e.Y, x, y, g.Gray16At(x, y).Y)
+			}
+		}
+	}
+
+	c := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	c.Set(0, 0, color.RGBA{200, 10, 0, 255})
+	for l, e := range map[Luminance]uint16{Red: 200 * 0x101, Green: 10 * 0x101, Blue: 0, MaxChannel: 200 * 0x101} {
+		if v := From(c, l).Pix[0]; v != e {
+			t.Errorf("Luminance %d: expecting %d got %d", l, e, v)
+		}
+	}
+}
@@ -11,8 +11,13 @@ type Gray16 struct {
 	// Pix holds the image's pixels, as gray values in big-endian format. The pixel at
 	// (x, y) is at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)].
 	Pix []uint16
-	// MaxVal is the highest value occurring in this image
-	MaxVal uint16
+	// MinVal and MaxVal are the lowest and highest values occurring in this
+	// image. They are exact after NewGray16, SubImage, UpdateRange and
+	// Normalise. Set and SetGray16 widen them to include the values written
+	// but cannot narrow them when an extreme value is overwritten, and writes
+	// to Pix leave them unchanged, so after such writes call UpdateRange to
+	// make them exact.
+	MinVal, MaxVal uint16
 	// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
 	Stride int
 	// Rect is the image's bounds.
@@ -48,6 +53,7 @@ func (p *Gray16) Set(x, y int, c color.Color) {
 	i := p.PixOffset(x, y)
 	c1 := color.Gray16Model.Convert(c).(color.Gray16)
 	p.Pix[i] = c1.Y
+	p.widen(c1.Y)
 }
 
 func (p *Gray16) SetGray16(x, y int, c color.Gray16) {
@@ -56,6 +62,44 @@ func (p *Gray16) SetGray16(x, y int, c color.Gray16) {
 	}
 	i := p.PixOffset(x, y)
 	p.Pix[i] = c.Y
+	p.widen(c.Y)
+}
+
+// widen widens the range of values to include v.
+func (p *Gray16) widen(v uint16) {
+	if v < p.MinVal {
+		p.MinVal = v
+	}
+	if v > p.MaxVal {
+		p.MaxVal = v
+	}
+}
+
+// Range returns the lowest and highest values of the pixels within the
+// bounds of the image. An empty image has the range 0 to 0.
+func (p *Gray16) Range() (min, max uint16) {
+	if p.Rect.Empty() {
+		return 0, 0
+	}
+	min = math.MaxUint16
+	w := p.Rect.Dx()
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for _, v := range p.Pix[i : i+w] {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+	}
+	return min, max
+}
+
+// UpdateRange sets MinVal and MaxVal to the range of values of the image.
+func (p *Gray16) UpdateRange() {
+	p.MinVal, p.MaxVal = p.Range()
 }
 
 // SubImage returns an image representing the portion of the image p visible
@@ -69,11 +113,13 @@ func (p *Gray16) SubImage(r image.Rectangle) image.Image {
 		return &Gray16{}
 	}
 	i := p.PixOffset(r.Min.X, r.Min.Y)
-	return &Gray16{
+	sub := &Gray16{
 		Pix:    p.Pix[i:],
 		Stride: p.Stride,
 		Rect:   r,
 	}
+	sub.UpdateRange()
+	return sub
 }
 
 // Opaque scans the entire image and reports whether it is fully opaque.
@@ -92,10 +138,24 @@ func NewGray16(r image.Rectangle) *Gray16 {
 	}
 }
 
+// Normalise stretches the values of the image linearly so that the lowest
+// becomes 0 and the highest math.MaxUint16. The range is recomputed first so
+// stale MinVal and MaxVal do not matter. An image whose pixels all have the
+// same value is left unchanged since there is no range to stretch.
 func (p *Gray16) Normalise() {
-	ratio := float64(math.MaxUint16) / float64(p.MaxVal)
-	for i, v := range p.Pix {
-		p.Pix[i] = uint16(float64(v) * ratio)
+	p.UpdateRange()
+	if p.MinVal == p.MaxVal {
+		return
+	}
+	min := uint32(p.MinVal)
+	span := uint32(p.MaxVal) - min
+	w := p.Rect.Dx()
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		row := p.Pix[i : i+w]
+		for j, v := range row {
+			row[j] = uint16(((uint32(v)-min)*math.MaxUint16 + span/2) / span)
+		}
 	}
-	p.MaxVal = math.MaxUint16
+	p.MinVal, p.MaxVal = 0, math.MaxUint16
 }
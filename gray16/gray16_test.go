@@ -0,0 +1,101 @@
+package gray16
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestRangeTracking(t *testing.T) {
+	g := NewGray16(image.Rect(0, 0, 4, 3))
+	if g.MinVal != 0 || g.MaxVal != 0 {
+		t.Fatalf("Expecting a new image to have range 0 to 0 got %d to %d", g.MinVal, g.MaxVal)
+	}
+	g.SetGray16(1, 1, color.Gray16{500})
+	g.Set(2, 1, color.Gray16{700})
+	if g.MinVal != 0 || g.MaxVal != 700 {
+		t.Errorf("Expecting writes to widen the range to 0 to 700 got %d to %d", g.MinVal, g.MaxVal)
+	}
+	for i := range g.Pix {
+		g.Pix[i] = 100
+	}
+	g.Pix[g.PixOffset(3, 2)] = 900
+	g.UpdateRange()
+	if g.MinVal != 100 || g.MaxVal != 900 {
+		t.Errorf("Expecting UpdateRange to give 100 to 900 got %d to %d", g.MinVal, g.MaxVal)
+	}
+	sub := g.SubImage(image.Rect(0, 0, 2, 2)).(*Gray16)
+	if sub.MinVal != 100 || sub.MaxVal != 100 {
+		t.Errorf("Expecting the sub image to have range 100 to 100 got %d to %d", sub.MinVal, sub.MaxVal)
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	g := NewGray16(image.Rect(0, 0, 3, 2))
+	copy(g.Pix, []uint16{100, 200, 300, 100, 100, 100})
+	// A stale range must not matter.
+	g.MaxVal = 0
+	g.Normalise()
+	expected := []uint16{0, 32768, math.MaxUint16, 0, 0, 0}
+	for i, e := range expected {
+		if g.Pix[i] != e {
+			t.Errorf("Expecting %d at %d got %d", e, i, g.Pix[i])
+		}
+	}
+	if g.MinVal != 0 || g.MaxVal != math.MaxUint16 {
+		t.Errorf("Expecting range 0 to %d got %d to %d", math.MaxUint16, g.MinVal, g.MaxVal)
+	}
+
+	// Constant images are left unchanged.
+	c := NewGray16(image.Rect(0, 0, 2, 2))
+	for i := range c.Pix {
+		c.Pix[i] = 42
+	}
+	c.Normalise()
+	for i, v := range c.Pix {
+		if v != 42 {
+			t.Errorf("Expecting a constant image to be unchanged got %d at %d", v, i)
+		}
+	}
+
+	// Normalising a sub image leaves the rest of the image alone.
+	g = NewGray16(image.Rect(0, 0, 4, 1))
+	copy(g.Pix, []uint16{7, 10, 20, 9})
+	g.SubImage(image.Rect(1, 0, 3, 1)).(*Gray16).Normalise()
+	expected = []uint16{7, 0, math.MaxUint16, 9}
+	for i, e := range expected {
+		if g.Pix[i] != e {
+			t.Errorf("Expecting %d at %d got %d", e, i, g.Pix[i])
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	g := NewGray16(image.Rect(0, 0, 5, 1))
+	copy(g.Pix, []uint16{100, 101, 150, 199, 200})
+	h := g.Histogram(4)
+	if h.Min != 100 || h.Max != 200 || len(h.Counts) != 4 {
+		t.Fatalf("Expecting 4 bins over 100 to 200 got %d over %d to %d", len(h.Counts), h.Min, h.Max)
+	}
+	expected := []int{2, 1, 0, 2}
+	for i, e := range expected {
+		if h.Counts[i] != e {
+			t.Errorf("Expecting %d in bin %d got %d", e, i, h.Counts[i])
+		}
+	}
+	if h.Total() != 5 {
+		t.Errorf("Expecting a total of 5 got %d", h.Total())
+	}
+	// Each bin's upper value lies in it and the next value in the next bin.
+	for i := 0; i < len(h.Counts)-1; i++ {
+		u := h.Upper(i)
+		if h.Bin(u) != i || h.Bin(u+1) != i+1 {
+			t.Errorf("Expecting %d to be the top of bin %d", u, i)
+		}
+	}
+	// Narrow ranges get a bin per value.
+	if h := NewGray16(image.Rect(0, 0, 3, 3)).Histogram(256); len(h.Counts) != 1 || h.Counts[0] != 9 {
+		t.Errorf("Expecting a constant image to have one full bin got %v", h.Counts)
+	}
+}
@@ -0,0 +1,70 @@
+package gray16
+
+// Histogram counts the pixels of an image in bins that divide the range of
+// its values evenly.
+type Histogram struct {
+	// Counts holds the number of pixels in each bin.
+	Counts []int
+	// Min and Max are the lowest and highest values of the image, the bins
+	// cover the values from Min to Max inclusive.
+	Min, Max uint16
+}
+
+// Histogram returns the histogram of the pixels within the bounds of the
+// image with the given number of bins, fewer than 1 bins gives 256. Bins are
+// at least one value wide, so an image with a narrow range of values gets
+// fewer bins than asked for.
+func (p *Gray16) Histogram(bins int) *Histogram {
+	if bins < 1 {
+		bins = 256
+	}
+	min, max := p.Range()
+	if span := int(max) - int(min) + 1; bins > span {
+		bins = span
+	}
+	h := &Histogram{Counts: make([]int, bins), Min: min, Max: max}
+	w := p.Rect.Dx()
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for _, v := range p.Pix[i : i+w] {
+			h.Counts[h.Bin(v)]++
+		}
+	}
+	return h
+}
+
+// Bin returns the bin that holds the value v, values outside the range of
+// the histogram are put in the first or last bin.
+func (h *Histogram) Bin(v uint16) int {
+	if v <= h.Min {
+		return 0
+	}
+	if v >= h.Max {
+		return len(h.Counts) - 1
+	}
+	return int(v-h.Min) * len(h.Counts) / h.span()
+}
+
+// Upper returns the highest value held by the bin, it is the threshold that
+// separates the values in bins up to and including bin from those above.
+func (h *Histogram) Upper(bin int) uint16 {
+	if bin >= len(h.Counts)-1 {
+		return h.Max
+	}
+	n := len(h.Counts)
+	return h.Min + uint16(((bin+1)*h.span()+n-1)/n-1)
+}
+
+// Total returns the number of pixels counted.
+func (h *Histogram) Total() int {
+	var total int
+	for _, c := range h.Counts {
+		total += c
+	}
+	return total
+}
+
+// span returns the number of values covered by the histogram.
+func (h *Histogram) span() int {
+	return int(h.Max) - int(h.Min) + 1
+}
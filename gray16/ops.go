@@ -0,0 +1,121 @@
+package gray16
+
+import (
+	"fmt"
+	"math"
+)
+
+// The pixel-wise operations below combine two images over the intersection
+// of their bounds, pixels being matched by their coordinates, and return a
+// new image of those bounds. Results outside the range of a uint16 saturate
+// at 0 or math.MaxUint16.
+
+// Add returns the sum of a and b.
+func Add(a, b *Gray16) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		if s := uint32(x) + uint32(y); s < math.MaxUint16 {
+			return uint16(s)
+		}
+		return math.MaxUint16
+	})
+}
+
+// Subtract returns a minus b, such as an image less its background.
+func Subtract(a, b *Gray16) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		if x > y {
+			return x - y
+		}
+		return 0
+	})
+}
+
+// AbsDiff returns the absolute difference of a and b, such as the change
+// between two frames.
+func AbsDiff(a, b *Gray16) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		if x > y {
+			return x - y
+		}
+		return y - x
+	})
+}
+
+// Multiply returns the product of a and b with math.MaxUint16 taken as 1, so
+// that multiplying by a mask keeps the pixels where the mask is white and
+// clears them where it is black.
+func Multiply(a, b *Gray16) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		return uint16((uint32(x)*uint32(y) + math.MaxUint16/2) / math.MaxUint16)
+	})
+}
+
+// Min returns the lower of a and b at each pixel.
+func Min(a, b *Gray16) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		if x < y {
+			return x
+		}
+		return y
+	})
+}
+
+// Max returns the higher of a and b at each pixel, such as the union of two
+// edge maps.
+func Max(a, b *Gray16) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		if x > y {
+			return x
+		}
+		return y
+	})
+}
+
+// Blend returns wa*a + wb*b + offset, rounded to the nearest value. Weights
+// of 1-t and t fade from a to b.
+func Blend(a *Gray16, wa float64, b *Gray16, wb, offset float64) *Gray16 {
+	return combine(a, b, func(x, y uint16) uint16 {
+		v := wa*float64(x) + wb*float64(y) + offset
+		switch {
+		case !(v > 0):
+			return 0
+		case v >= math.MaxUint16:
+			return math.MaxUint16
+		}
+		return uint16(v + 0.5)
+	})
+}
+
+// Lookup returns g with each value v replaced by lut[v]. The table must have
+// an entry for every value, 65536 in all.
+func Lookup(g *Gray16, lut []uint16) (*Gray16, error) {
+	if len(lut) != math.MaxUint16+1 {
+		return nil, fmt.Errorf("gray16: lookup table of %d entries, expecting %d", len(lut), math.MaxUint16+1)
+	}
+	out := NewGray16(g.Rect)
+	w := g.Rect.Dx()
+	for y := g.Rect.Min.Y; y < g.Rect.Max.Y; y++ {
+		i, o := g.PixOffset(g.Rect.Min.X, y), out.PixOffset(g.Rect.Min.X, y)
+		for x, v := range g.Pix[i : i+w] {
+			out.Pix[o+x] = lut[v]
+		}
+	}
+	out.UpdateRange()
+	return out, nil
+}
+
+// combine returns the image of f applied to the pixels of a and b over the
+// intersection of their bounds.
+func combine(a, b *Gray16, f func(x, y uint16) uint16) *Gray16 {
+	r := a.Rect.Intersect(b.Rect)
+	out := NewGray16(r)
+	w := r.Dx()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i, j, o := a.PixOffset(r.Min.X, y), b.PixOffset(r.Min.X, y), out.PixOffset(r.Min.X, y)
+		for x, v := range a.Pix[i : i+w] {
+			out.Pix[o+x] = f(v, b.Pix[j+x])
+		}
+	}
+	out.UpdateRange()
+	return out
+}
@@ -0,0 +1,60 @@
+package gray16
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestOps(t *testing.T) {
+	// a and b overlap in the two pixels from (1, 0) to (3, 1).
+	a := NewGray16(image.Rect(0, 0, 3, 1))
+	copy(a.Pix, []uint16{7, 60000, 100})
+	b := NewGray16(image.Rect(1, 0, 4, 1))
+	copy(b.Pix, []uint16{10000, 300, 9})
+	for name, c := range map[string]struct {
+		out      *Gray16
+		expected []uint16
+	}{
+		"add":      {Add(a, b), []uint16{math.MaxUint16, 400}},
+		"subtract": {Subtract(a, b), []uint16{50000, 0}},
+		"absdiff":  {AbsDiff(a, b), []uint16{50000, 200}},
+		"multiply": {Multiply(a, b), []uint16{9155, 0}},
+		"min":      {Min(a, b), []uint16{10000, 100}},
+		"max":      {Max(a, b), []uint16{60000, 300}},
+		"blend":    {Blend(a, 0.25, b, 0.75, 0), []uint16{22500, 250}},
+		"offset":   {Blend(a, 1, b, -1, 10), []uint16{50010, 0}},
+	} {
+		if c.out.Rect != image.Rect(1, 0, 3, 1) {
+			t.Errorf("%s: expecting the intersection got %v", name, c.out.Rect)
+			continue
+		}
+		for i, e := range c.expected {
+			if v := c.out.Gray16At(1+i, 0).Y; v != e {
+				t.Errorf("%s: expecting %d at %d got %d", name, e, 1+i, v)
+			}
+		}
+		if min, max := c.out.Range(); c.out.MinVal != min || c.out.MaxVal != max {
+			t.Errorf("%s: expecting the range to be tracked", name)
+		}
+	}
+
+	if d := Add(a, NewGray16(image.Rect(5, 5, 6, 6))); !d.Rect.Empty() {
+		t.Errorf("Expecting disjoint images to give an empty image got %v", d.Rect)
+	}
+
+	lut := make([]uint16, math.MaxUint16+1)
+	for i := range lut {
+		lut[i] = math.MaxUint16 - uint16(i)
+	}
+	inv, err := Lookup(b.SubImage(image.Rect(2, 0, 4, 1)).(*Gray16), lut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Rect != image.Rect(2, 0, 4, 1) || inv.Pix[0] != math.MaxUint16-300 || inv.Pix[1] != math.MaxUint16-9 {
+		t.Errorf("Unexpected lookup result %v over %v", inv.Pix, inv.Rect)
+	}
+	if _, err := Lookup(b, lut[:256]); err == nil {
+		t.Error("Expecting an error for a short lookup table")
+	}
+}
@@ -0,0 +1,216 @@
+// Package gray32f provides a single channel image of float32 values, for
+// intermediate results such as gradients and blurred images that need
+// negative and fractional values.
+package gray32f
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// Color is a float32 gray value. Values from 0 to 1 are shown from black to
+// white, values outside that range are clamped when the colour is converted
+// to RGBA.
+type Color struct {
+	Y float32
+}
+
+func (c Color) RGBA() (r, g, b, a uint32) {
+	y := c.Y
+	if !(y > 0) {
+		y = 0
+	} else if y > 1 {
+		y = 1
+	}
+	v := uint32(y*math.MaxUint16 + 0.5)
+	return v, v, v, math.MaxUint16
+}
+
+// Model converts colours to Color by their luminance, scaled to 0 to 1.
+var Model = color.ModelFunc(func(c color.Color) color.Color {
+	if g, ok := c.(Color); ok {
+		return g
+	}
+	y := color.Gray16Model.Convert(c).(color.Gray16).Y
+	return Color{float32(y) / math.MaxUint16}
+})
+
+// Gray32f is an in-memory image whose At method returns Color values.
+type Gray32f struct {
+	// Pix holds the image's pixels. The pixel at (x, y) is at
+	// Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)].
+	Pix []float32
+	// Stride is the Pix stride (in pixels) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewGray32f returns a new Gray32f with the given bounds.
+func NewGray32f(r image.Rectangle) *Gray32f {
+	w, h := r.Dx(), r.Dy()
+	return &Gray32f{
+		Pix:    make([]float32, w*h),
+		Stride: w,
+		Rect:   r,
+	}
+}
+
+func (p *Gray32f) ColorModel() color.Model { return Model }
+
+func (p *Gray32f) Bounds() image.Rectangle { return p.Rect }
+
+func (p *Gray32f) At(x, y int) color.Color {
+	return p.Gray32fAt(x, y)
+}
+
+func (p *Gray32f) Gray32fAt(x, y int) Color {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return Color{}
+	}
+	return Color{p.Pix[p.PixOffset(x, y)]}
+}
+
+// PixOffset returns the index of the element of Pix that corresponds to the
+// pixel at (x, y).
+func (p *Gray32f) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x - p.Rect.Min.X)
+}
+
+func (p *Gray32f) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.Pix[p.PixOffset(x, y)] = Model.Convert(c).(Color).Y
+}
+
+func (p *Gray32f) SetGray32f(x, y int, c Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.Pix[p.PixOffset(x, y)] = c.Y
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *Gray32f) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are image.Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &Gray32f{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &Gray32f{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *Gray32f) Opaque() bool {
+	return true
+}
+
+// Range returns the lowest and highest values of the pixels within the
+// bounds of the image, ignoring NaNs. An empty image has the range 0 to 0.
+func (p *Gray32f) Range() (min, max float32) {
+	min, max = float32(math.Inf(1)), float32(math.Inf(-1))
+	w := p.Rect.Dx()
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for _, v := range p.Pix[i : i+w] {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+	}
+	if min > max {
+		return 0, 0
+	}
+	return min, max
+}
+
+// Mode selects how values are converted between Gray16 and Gray32f.
+type Mode int
+
+const (
+	// Clamp keeps values as they are, so a Gray16 value of 1000 becomes
+	// 1000. Converting to Gray16 rounds values and clamps them to 0 to
+	// math.MaxUint16.
+	Clamp Mode = iota
+	// Unit scales the Gray16 range 0 to math.MaxUint16 to 0 to 1, the range
+	// displayed by Color. Converting to Gray16 clamps values to 0 to 1 first.
+	Unit
+	// Stretch maps the range of values of the image linearly onto the range
+	// of the destination, 0 to 1 for Gray32f and 0 to math.MaxUint16 for
+	// Gray16, so the lowest value becomes 0. It suits signed results such as
+	// gradients. An image of a single value becomes 0.
+	Stretch
+)
+
+// FromGray16 returns g converted to a Gray32f with the same bounds.
+func FromGray16(g *gray16.Gray16, m Mode) *Gray32f {
+	out := NewGray32f(g.Rect)
+	var offset, scale float32 = 0, 1
+	switch m {
+	case Unit:
+		scale = 1.0 / math.MaxUint16
+	case Stretch:
+		min, max := g.Range()
+		offset, scale = float32(min), 0
+		if max > min {
+			scale = 1 / float32(max-min)
+		}
+	}
+	w := g.Rect.Dx()
+	for y := g.Rect.Min.Y; y < g.Rect.Max.Y; y++ {
+		i, o := g.PixOffset(g.Rect.Min.X, y), out.PixOffset(g.Rect.Min.X, y)
+		for x, v := range g.Pix[i : i+w] {
+			out.Pix[o+x] = (float32(v) - offset) * scale
+		}
+	}
+	return out
+}
+
+// ToGray16 returns p converted to a Gray16 with the same bounds. NaNs become
+// 0.
+func (p *Gray32f) ToGray16(m Mode) *gray16.Gray16 {
+	out := gray16.NewGray16(p.Rect)
+	var offset, scale float64 = 0, 1
+	switch m {
+	case Unit:
+		scale = math.MaxUint16
+	case Stretch:
+		min, max := p.Range()
+		offset, scale = float64(min), 0
+		if max > min {
+			scale = math.MaxUint16 / (float64(max) - float64(min))
+		}
+	}
+	w := p.Rect.Dx()
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i, o := p.PixOffset(p.Rect.Min.X, y), out.PixOffset(p.Rect.Min.X, y)
+		for x, v := range p.Pix[i : i+w] {
+			s := (float64(v) - offset) * scale
+			switch {
+			case !(s > 0):
+				out.Pix[o+x] = 0
+			case s >= math.MaxUint16:
+				out.Pix[o+x] = math.MaxUint16
+			default:
+				out.Pix[o+x] = uint16(s + 0.5)
+			}
+		}
+	}
+	out.UpdateRange()
+	return out
+}
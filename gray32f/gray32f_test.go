@@ -0,0 +1,101 @@
+package gray32f
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+var _ draw.Image = (*Gray32f)(nil)
+
+func TestColor(t *testing.T) {
+	for _, c := range []struct {
+		y        float32
+		expected uint32
+	}{
+		{-2, 0},
+		{0, 0},
+		{0.5, 32768},
+		{1, math.MaxUint16},
+		{3, math.MaxUint16},
+		{float32(math.NaN()), 0},
+	} {
+		if r, _, _, a := (Color{c.y}).RGBA(); r != c.expected || a != math.MaxUint16 {
+			t.Errorf("Expecting %g to give %d got %d", c.y, c.expected, r)
+		}
+	}
+	if y := Model.Convert(color.Gray16{math.MaxUint16}).(Color).Y; y != 1 {
+		t.Errorf("Expecting white to convert to 1 got %g", y)
+	}
+}
+
+func TestSubImage(t *testing.T) {
+	p := NewGray32f(image.Rect(0, 0, 4, 4))
+	p.SetGray32f(2, 1, Color{-1.5})
+	s := p.SubImage(image.Rect(1, 1, 3, 5)).(*Gray32f)
+	if s.Rect != image.Rect(1, 1, 3, 4) {
+		t.Errorf("Expecting the bounds to be clipped got %v", s.Rect)
+	}
+	if y := s.Gray32fAt(2, 1).Y; y != -1.5 {
+		t.Errorf("Expecting the sub image to share pixels got %g", y)
+	}
+	s.SetGray32f(1, 3, Color{7})
+	if y := p.Gray32fAt(1, 3).Y; y != 7 {
+		t.Errorf("Expecting writes to the sub image to be shared got %g", y)
+	}
+	if y := s.Gray32fAt(0, 0).Y; y != 0 {
+		t.Errorf("Expecting 0 outside the bounds got %g", y)
+	}
+	if e := p.SubImage(image.Rect(5, 5, 6, 6)); !e.Bounds().Empty() {
+		t.Errorf("Expecting an empty image got %v", e.Bounds())
+	}
+	if min, max := s.Range(); min != -1.5 || max != 7 {
+		t.Errorf("Expecting the range -1.5 to 7 got %g to %g", min, max)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(0, 0, 3, 1))
+	copy(g.Pix, []uint16{1000, 2000, 3000})
+	g.UpdateRange()
+	for _, c := range []struct {
+		mode     Mode
+		expected []float32
+	}{
+		{Clamp, []float32{1000, 2000, 3000}},
+		{Unit, []float32{1000.0 / math.MaxUint16, 2000.0 / math.MaxUint16, 3000.0 / math.MaxUint16}},
+		{Stretch, []float32{0, 0.5, 1}},
+	} {
+		f := FromGray16(g, c.mode)
+		for i, e := range c.expected {
+			if math.Abs(float64(f.Pix[i]-e)) > 1e-6 {
+				t.Errorf("Mode %d: expecting %g at %d got %g", c.mode, e, i, f.Pix[i])
+			}
+		}
+	}
+
+	f := NewGray32f(image.Rect(0, 0, 4, 1))
+	copy(f.Pix, []float32{-1, 0.25, 2.6, 70000})
+	for _, c := range []struct {
+		mode     Mode
+		expected []uint16
+	}{
+		{Clamp, []uint16{0, 0, 3, math.MaxUint16}},
+		{Unit, []uint16{0, 16384, math.MaxUint16, math.MaxUint16}},
+		{Stretch, []uint16{0, 1, 3, math.MaxUint16}},
+	} {
+		g := f.ToGray16(c.mode)
+		for i, e := range c.expected {
+			if g.Pix[i] != e {
+				t.Errorf("Mode %d: expecting %d at %d got %d", c.mode, e, i, g.Pix[i])
+			}
+		}
+		if g.MaxVal != c.expected[3] {
+			t.Errorf("Mode %d: expecting the range to be tracked got %d", c.mode, g.MaxVal)
+		}
+	}
+}
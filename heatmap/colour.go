@@ -0,0 +1,96 @@
+package heatmap
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// ColourMap maps a value in [0, 1] to a colour.
+type ColourMap func(t float64) color.RGBA
+
+// viridisStops and infernoStops sample the matplotlib colour maps of the
+// same names at intervals of 1/8.
+var (
+	viridisStops = []color.RGBA{
+		{68, 1, 84, 255}, {71, 45, 123, 255}, {59, 82, 139, 255},
+		{44, 114, 142, 255}, {33, 145, 140, 255}, {40, 174, 128, 255},
+		{94, 201, 98, 255}, {173, 220, 48, 255}, {253, 231, 37, 255},
+	}
+	infernoStops = []color.RGBA{
+		{0, 0, 4, 255}, {31, 12, 72, 255}, {85, 15, 109, 255},
+		{136, 34, 106, 255}, {186, 54, 85, 255}, {227, 89, 51, 255},
+		{249, 142, 9, 255}, {249, 203, 53, 255}, {252, 255, 164, 255},
+	}
+)
+
+// Viridis is the perceptually uniform viridis colour map, running from dark
+// blue through green to yellow.
+func Viridis(t float64) color.RGBA {
+	return interpolate(viridisStops, t)
+}
+
+// Inferno is the perceptually uniform inferno colour map, running from black
+// through red to pale yellow.
+func Inferno(t float64) color.RGBA {
+	return interpolate(infernoStops, t)
+}
+
+// Jet is the rainbow colour map running from dark blue through cyan, yellow
+// and red to dark red. It is not perceptually uniform and can suggest
+// structure that is not there, but is familiar from other tools.
+func Jet(t float64) color.RGBA {
+	t = clamp(t)
+	channel := func(centre float64) uint8 {
+		return uint8(255*clamp(1.5-math.Abs(4*t-centre)) + 0.5)
+	}
+	return color.RGBA{channel(3), channel(2), channel(1), 255}
+}
+
+// Grayscale maps values to shades of gray from black to white.
+func Grayscale(t float64) color.RGBA {
+	v := uint8(255*clamp(t) + 0.5)
+	return color.RGBA{v, v, v, 255}
+}
+
+// ByName returns the colour map with the given name, one of viridis,
+// inferno, jet or grayscale.
+func ByName(name string) (ColourMap, error) {
+	switch name {
+	case "viridis":
+		return Viridis, nil
+	case "inferno":
+		return Inferno, nil
+	case "jet":
+		return Jet, nil
+	case "grayscale", "gray", "grey":
+		return Grayscale, nil
+	}
+	return nil, fmt.Errorf("heatmap: unknown colour map %q", name)
+}
+
+// interpolate returns the colour at t of the evenly spaced stops.
+func interpolate(stops []color.RGBA, t float64) color.RGBA {
+	f := clamp(t) * float64(len(stops)-1)
+	i := int(f)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	f -= float64(i)
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-f) + float64(b)*f + 0.5)
+	}
+	a, b := stops[i], stops[i+1]
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// clamp limits t to [0, 1], NaN is mapped to 0.
+func clamp(t float64) float64 {
+	if !(t > 0) {
+		return 0
+	}
+	if t > 1 {
+		return 1
+	}
+	return t
+}
@@ -0,0 +1,313 @@
+// Package heatmap renders gray16 images, such as hough accumulators, in false
+// colour so that the structure of low values remains visible.
+package heatmap
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+
+	"github.com/piersy/hough-go/canvas"
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/hough"
+)
+
+// Scale selects how values are mapped onto the colour map.
+type Scale int
+
+const (
+	// Linear maps the range of values linearly onto the colour map.
+	Linear Scale = iota
+	// Log maps the logarithm of the values above the minimum onto the colour
+	// map, which brings out low values next to strong peaks.
+	Log
+	// Equalise maps values through their cumulative histogram so that each
+	// colour covers roughly the same number of pixels.
+	Equalise
+)
+
+// ParseScale returns the scale with the given name, one of linear, log or
+// equalise.
+func ParseScale(name string) (Scale, error) {
+	switch name {
+	case "linear":
+		return Linear, nil
+	case "log":
+		return Log, nil
+	case "equalise", "equalize":
+		return Equalise, nil
+	}
+	return 0, fmt.Errorf("heatmap: unknown scale %q", name)
+}
+
+// Option configures the rendering.
+type Option func(*config)
+
+type config struct {
+	colours ColourMap
+	scale   Scale
+	bar     bool
+	ticks   bool
+}
+
+// Colours sets the colour map, the default is Viridis.
+func Colours(m ColourMap) Option {
+	return func(c *config) {
+		c.colours = m
+	}
+}
+
+// Scaling sets how values are mapped onto the colour map, the default is
+// Linear.
+func Scaling(s Scale) Option {
+	return func(c *config) {
+		c.scale = s
+	}
+}
+
+// Bar adds a colour bar to the right of the image labelled with the lowest
+// and highest values.
+func Bar() Option {
+	return func(c *config) {
+		c.bar = true
+	}
+}
+
+// Ticks adds ticks and labels along the left and bottom edges of the image.
+// They are in pixel coordinates when rendering with Render and in the
+// distance in pixels and the angle in degrees of the lines when rendering
+// with RenderAccumulator.
+func Ticks() Option {
+	return func(c *config) {
+		c.ticks = true
+	}
+}
+
+func (c *config) validate() error {
+	if c.colours == nil {
+		return errors.New("heatmap: nil colour map")
+	}
+	if c.scale < Linear || c.scale > Equalise {
+		return fmt.Errorf("heatmap: unknown scale %d", c.scale)
+	}
+	return nil
+}
+
+// axis maps the values along an edge of the image to pixels, the value min
+// is at the start of the first pixel and max at the end of the last.
+type axis struct {
+	min, max float64
+	// suffix is appended to the tick labels.
+	suffix string
+}
+
+// Render returns the image g in false colour.
+func Render(g *gray16.Gray16, opts ...Option) (*image.RGBA, error) {
+	if g == nil {
+		return nil, errors.New("heatmap: nil image")
+	}
+	b := g.Rect
+	return render(g,
+		axis{min: float64(b.Min.X), max: float64(b.Max.X)},
+		axis{min: float64(b.Min.Y), max: float64(b.Max.Y)},
+		opts)
+}
+
+// RenderAccumulator returns the accumulator a in false colour, its ticks are
+// labelled with the angle in degrees and the distance in pixels of the lines.
+func RenderAccumulator(a *hough.Accumulator, opts ...Option) (*image.RGBA, error) {
+	if a == nil || a.Gray16 == nil {
+		return nil, errors.New("heatmap: nil accumulator")
+	}
+	return render(a.Gray16,
+		axis{min: a.MinAngle * 180 / math.Pi, max: a.MaxAngle * 180 / math.Pi, suffix: "°"},
+		axis{min: a.MinDistance, max: a.MaxDistance},
+		opts)
+}
+
+// Sizes, in pixels, of the parts of the rendering around the image.
+const (
+	padding  = 4
+	tickLen  = 3
+	barGap   = 8
+	barWidth = 10
+)
+
+func render(g *gray16.Gray16, xAxis, yAxis axis, opts []Option) (*image.RGBA, error) {
+	c := config{colours: Viridis}
+	for _, o := range opts {
+		o(&c)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	b := g.Rect
+	if b.Empty() {
+		return nil, errors.New("heatmap: empty image")
+	}
+	min, max := g.Range()
+	colours := c.lookup(g, min, max)
+
+	// Lay out the image with room for the ticks and colour bar.
+	var xTicks, yTicks []tick
+	left, top, right, bottom := 0, 0, 0, 0
+	if c.ticks || c.bar {
+		left, top, right, bottom = padding, padding, padding, padding
+	}
+	if c.ticks {
+		xTicks = xAxis.ticks(b.Dx())
+		yTicks = yAxis.ticks(b.Dy())
+		width := 0
+		for _, t := range yTicks {
+			if w := canvas.TextWidth(t.label); w > width {
+				width = w
+			}
+		}
+		left += width + tickLen + 1
+		bottom += tickLen + 1 + canvas.GlyphHeight
+		// Leave room for labels centred on ticks near the corners.
+		if len(xTicks) > 0 {
+			left = maxInt(left, padding+canvas.TextWidth(xTicks[0].label)/2)
+			right = maxInt(right, padding+canvas.TextWidth(xTicks[len(xTicks)-1].label)/2)
+		}
+	}
+	minLabel, maxLabel := strconv.Itoa(int(min)), strconv.Itoa(int(max))
+	if c.bar {
+		right += barGap + barWidth + 2 + maxInt(canvas.TextWidth(minLabel), canvas.TextWidth(maxLabel))
+	}
+	out := image.NewRGBA(image.Rect(0, 0, left+b.Dx()+right, top+b.Dy()+bottom))
+	if c.ticks || c.bar {
+		draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := g.Pix[g.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			out.SetRGBA(left+x, top+y-b.Min.Y, colours[row[x]-min])
+		}
+	}
+
+	ctx := canvas.NewTextContext()
+	ctx.Color(color.Black)
+	if c.ticks {
+		base := top + b.Dy()
+		for _, t := range xTicks {
+			x := left + t.pos
+			for i := 0; i < tickLen; i++ {
+				out.Set(x, base+i, color.Black)
+			}
+			ctx.Text(image.Pt(x-canvas.TextWidth(t.label)/2, base+tickLen+1), t.label)
+		}
+		for _, t := range yTicks {
+			y := top + t.pos
+			for i := 1; i <= tickLen; i++ {
+				out.Set(left-i, y, color.Black)
+			}
+			ctx.Text(image.Pt(left-tickLen-1-canvas.TextWidth(t.label), y-canvas.GlyphHeight/2), t.label)
+		}
+	}
+	if c.bar {
+		x0 := left + b.Dx() + barGap
+		for y := 0; y < b.Dy(); y++ {
+			col := c.colours(1 - (float64(y)+0.5)/float64(b.Dy()))
+			for x := x0; x < x0+barWidth; x++ {
+				out.SetRGBA(x, top+y, col)
+			}
+		}
+		ctx.Text(image.Pt(x0+barWidth+2, top), maxLabel)
+		ctx.Text(image.Pt(x0+barWidth+2, top+b.Dy()-canvas.GlyphHeight), minLabel)
+	}
+	ctx.Render(out)
+	return out, nil
+}
+
+// lookup returns the colour of each value from min to max, indexed by the
+// value less min.
+func (c *config) lookup(g *gray16.Gray16, min, max uint16) []color.RGBA {
+	n := int(max) - int(min) + 1
+	scaled := make([]float64, n)
+	span := float64(max) - float64(min)
+	switch c.scale {
+	case Linear:
+		for i := range scaled {
+			scaled[i] = float64(i) / span
+		}
+	case Log:
+		for i := range scaled {
+			scaled[i] = math.Log1p(float64(i)) / math.Log1p(span)
+		}
+	case Equalise:
+		counts := make([]int, n)
+		b := g.Rect
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for _, v := range g.Pix[g.PixOffset(b.Min.X, y):][:b.Dx()] {
+				counts[v-min]++
+			}
+		}
+		// The lowest value is mapped to 0 and the highest to 1.
+		total := b.Dx()*b.Dy() - counts[0]
+		cumulative := 0
+		for i := 1; i < n; i++ {
+			cumulative += counts[i]
+			scaled[i] = float64(cumulative) / float64(total)
+		}
+	}
+	colours := make([]color.RGBA, n)
+	for i, s := range scaled {
+		if n == 1 {
+			s = 0
+		}
+		colours[i] = c.colours(s)
+	}
+	return colours
+}
+
+// tick is a labelled position along an axis.
+type tick struct {
+	pos   int
+	label string
+}
+
+// ticks returns around five ticks at round values along the axis of n
+// pixels.
+func (a axis) ticks(n int) []tick {
+	span := a.max - a.min
+	raw := span / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * magnitude
+	for _, m := range []float64{5, 2, 1} {
+		if m*magnitude >= raw {
+			step = m * magnitude
+		}
+	}
+	decimals := 0
+	if step < 1 {
+		decimals = int(math.Ceil(-math.Log10(step)))
+	}
+	var ticks []tick
+	first := math.Ceil(a.min / step)
+	for i := 0.0; (first+i)*step <= a.max; i++ {
+		v := (first + i) * step
+		if math.Abs(v) < step*1e-9 {
+			v = 0
+		}
+		pos := int((v - a.min) / span * float64(n))
+		if pos >= n {
+			pos = n - 1
+		}
+		label := strconv.FormatFloat(v, 'f', decimals, 64)
+		ticks = append(ticks, tick{pos, label + a.suffix})
+	}
+	return ticks
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
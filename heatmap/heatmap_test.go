@@ -0,0 +1,125 @@
+package heatmap
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/hough"
+)
+
+func TestColourMaps(t *testing.T) {
+	cases := []struct {
+		name      string
+		low, high color.RGBA
+	}{
+		{"viridis", color.RGBA{68, 1, 84, 255}, color.RGBA{253, 231, 37, 255}},
+		{"inferno", color.RGBA{0, 0, 4, 255}, color.RGBA{252, 255, 164, 255}},
+		{"jet", color.RGBA{0, 0, 128, 255}, color.RGBA{128, 0, 0, 255}},
+		{"grayscale", color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}},
+	}
+	for _, c := range cases {
+		m, err := ByName(c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l, h := m(0), m(1); l != c.low || h != c.high {
+			t.Errorf("%s: expecting %v to %v got %v to %v", c.name, c.low, c.high, l, h)
+		}
+		// Values outside [0, 1] are clamped.
+		if m(-1) != c.low || m(2) != c.high || m(math.NaN()) != c.low {
+			t.Errorf("%s: expecting values outside [0, 1] to be clamped", c.name)
+		}
+	}
+	if _, err := ByName("rainbow"); err == nil {
+		t.Error("Expecting an error for an unknown colour map")
+	}
+}
+
+// ramp returns a 4x1 image holding the values.
+func ramp(values ...uint16) *gray16.Gray16 {
+	g := gray16.NewGray16(image.Rect(0, 0, len(values), 1))
+	copy(g.Pix, values)
+	return g
+}
+
+func TestScaling(t *testing.T) {
+	g := ramp(10, 20, 20, 1010)
+	cases := []struct {
+		scale    Scale
+		expected []float64
+	}{
+		{Linear, []float64{0, 0.01, 0.01, 1}},
+		{Log, []float64{0, math.Log(11) / math.Log(1001), math.Log(11) / math.Log(1001), 1}},
+		// Two thirds of the pixels above the lowest are at or below 20.
+		{Equalise, []float64{0, 2.0 / 3, 2.0 / 3, 1}},
+	}
+	for _, c := range cases {
+		out, err := Render(g, Colours(Grayscale), Scaling(c.scale))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.Bounds() != g.Bounds() {
+			t.Fatalf("Expecting bounds %v got %v", g.Bounds(), out.Bounds())
+		}
+		for x, e := range c.expected {
+			if v := out.RGBAAt(x, 0); v != Grayscale(e) {
+				t.Errorf("Scale %d: expecting %v at %d got %v", c.scale, Grayscale(e), x, v)
+			}
+		}
+	}
+	// A constant image does not divide by zero.
+	out, err := Render(ramp(5, 5), Colours(Grayscale))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := out.RGBAAt(0, 0); v != Grayscale(0) {
+		t.Errorf("Expecting a constant image to be black got %v", v)
+	}
+}
+
+func TestRenderAccumulator(t *testing.T) {
+	acc := &hough.Accumulator{
+		Gray16:      gray16.NewGray16(image.Rect(0, 0, 180, 100)),
+		MinAngle:    0,
+		MaxAngle:    math.Pi,
+		MinDistance: -50,
+		MaxDistance: 50,
+	}
+	acc.Pix[acc.PixOffset(90, 50)] = 100
+	plain, err := RenderAccumulator(acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decorated, err := RenderAccumulator(acc, Ticks(), Bar(), Scaling(Log), Colours(Inferno))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Bounds() != acc.Bounds() {
+		t.Errorf("Expecting an undecorated rendering the size of the accumulator got %v", plain.Bounds())
+	}
+	if d := decorated.Bounds(); d.Dx() <= 180+barWidth || d.Dy() <= 100 {
+		t.Errorf("Expecting room for the ticks and colour bar got %v", d)
+	}
+
+	ticks := axis{min: 0, max: 180, suffix: "°"}.ticks(180)
+	var labels []string
+	for _, tk := range ticks {
+		labels = append(labels, tk.label)
+	}
+	expected := []string{"0°", "50°", "100°", "150°"}
+	if len(ticks) != len(expected) {
+		t.Fatalf("Expecting ticks %v got %v", expected, labels)
+	}
+	for i, e := range expected {
+		if labels[i] != e || ticks[i].pos != []int{0, 50, 100, 150}[i] {
+			t.Errorf("Expecting tick %s at %d got %+v", e, []int{0, 50, 100, 150}[i], ticks[i])
+		}
+	}
+
+	if _, err := Render(acc.Gray16, Scaling(Scale(7))); err == nil {
+		t.Error("Expecting an error for an unknown scale")
+	}
+}
@@ -0,0 +1,74 @@
+package hough
+
+import (
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/point"
+)
+
+// Accumulator is the result of a Hough transform. Each column of the
+// embedded image holds the votes for lines of a single angle and each row the
+// votes for lines at a single perpendicular distance from Origin.
+type Accumulator struct {
+	*gray16.Gray16
+	// MinAngle and MaxAngle give the half open range of line angles, in
+	// radians, covered by the columns.
+	MinAngle, MaxAngle float64
+	// MinDistance and MaxDistance give the range of perpendicular line
+	// distances from Origin covered by the rows. Unless the transform was
+	// restricted to a region of interest this is [-d, d] where d is the
+	// furthest any point of Input lies from Origin.
+	MinDistance, MaxDistance float64
+	// Origin is the point in the input from which distances are measured.
+	Origin point.Point
+	// Input is the bounds of the transformed image.
+	Input image.Rectangle
+}
+
+func newAccumulator(input image.Rectangle, c config) *Accumulator {
+	// The max distance from origin, used for normalising the distance from
+	// the source image to the size of the accumulator.
+	var maxDistance float64
+	for _, corner := range []image.Point{
+		input.Min, input.Max, image.Pt(input.Min.X, input.Max.Y), image.Pt(input.Max.X, input.Min.Y),
+	} {
+		dx := float64(corner.X) - c.origin.X
+		dy := float64(corner.Y) - c.origin.Y
+		maxDistance = math.Max(maxDistance, math.Sqrt(dx*dx+dy*dy))
+	}
+	return &Accumulator{
+		Gray16:      gray16.NewGray16(image.Rect(0, 0, c.angles, c.distances)),
+		MinAngle:    c.minAngle,
+		MaxAngle:    c.maxAngle,
+		MinDistance: -maxDistance,
+		MaxDistance: maxDistance,
+		Origin:      c.origin,
+		Input:       input,
+	}
+}
+
+// Angle converts an x coordinate in the accumulator into a line angle in
+// radians.
+func (a *Accumulator) Angle(x float64) float64 {
+	return a.MinAngle + x*(a.MaxAngle-a.MinAngle)/float64(a.Rect.Dx())
+}
+
+// Distance converts a y coordinate in the accumulator into a perpendicular
+// line distance from Origin.
+func (a *Accumulator) Distance(y float64) float64 {
+	return a.MinDistance + y*(a.MaxDistance-a.MinDistance)/float64(a.Rect.Dy())
+}
+
+// AngleBin is the inverse of Angle, it returns the x coordinate in the
+// accumulator of the given line angle.
+func (a *Accumulator) AngleBin(angle float64) float64 {
+	return (angle - a.MinAngle) * float64(a.Rect.Dx()) / (a.MaxAngle - a.MinAngle)
+}
+
+// DistanceBin is the inverse of Distance, it returns the y coordinate in the
+// accumulator of the given line distance.
+func (a *Accumulator) DistanceBin(distance float64) float64 {
+	return (distance - a.MinDistance) * float64(a.Rect.Dy()) / (a.MaxDistance - a.MinDistance)
+}
@@ -0,0 +1,106 @@
+package hough
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// csvCorner matches the label of the first cell of the header, which names
+// the axes of the table and gives the range of each, as in
+// rho[-10,10)\theta[0,3.14159). Files written before the ranges were added
+// have just rho\theta.
+var csvCorner = regexp.MustCompile(`^rho\[([^,]*),([^)]*)\)\\theta\[([^,]*),([^)]*)\)$`)
+
+// WriteCSV writes the votes of the accumulator to w as CSV. The first row
+// holds the half open ranges of the distances and angles, as in
+// rho[-10,10)\theta[0,3.14159), followed by the angle of each column in
+// radians, Angle(t). Each following row starts with the distance of the row,
+// Distance(d), followed by its votes.
+func (a *Accumulator) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, a.Rect.Dx()+1)
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	record[0] = `rho[` + f(a.MinDistance) + `,` + f(a.MaxDistance) + `)\theta[` + f(a.MinAngle) + `,` + f(a.MaxAngle) + `)`
+	for i := 1; i < len(record); i++ {
+		record[i] = f(a.Angle(float64(i - 1)))
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		record[0] = f(a.Distance(float64(y - a.Rect.Min.Y)))
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			record[x-a.Rect.Min.X+1] = strconv.Itoa(int(a.Pix[a.PixOffset(x, y)]))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads an accumulator written by WriteCSV from r. Votes are rounded
+// and saturated to 16 bits and the axes read as by ReadNPZ, from the ranges
+// in the first cell or, where it holds none, from the values of the axes. The
+// Origin and Input are not held in the CSV and are left zero.
+func ReadCSV(r io.Reader) (*Accumulator, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("hough: reading csv: %v", err)
+	}
+	if len(records) < 2 || len(records[0]) < 2 {
+		return nil, fmt.Errorf("hough: csv has no votes")
+	}
+	parse := func(row, col int) (float64, error) {
+		v, err := strconv.ParseFloat(records[row][col], 64)
+		if err != nil {
+			return 0, fmt.Errorf("hough: csv row %d column %d: %v", row+1, col+1, err)
+		}
+		return v, nil
+	}
+
+	theta := make([]float64, len(records[0])-1)
+	for i := range theta {
+		if theta[i], err = parse(0, i+1); err != nil {
+			return nil, err
+		}
+	}
+	var rhoRange, thetaRange []float64
+	if m := csvCorner.FindStringSubmatch(records[0][0]); m != nil {
+		bounds := make([]float64, 4)
+		for i := range bounds {
+			if bounds[i], err = strconv.ParseFloat(m[i+1], 64); err != nil {
+				return nil, fmt.Errorf("hough: csv axis ranges %q: %v", records[0][0], err)
+			}
+		}
+		rhoRange, thetaRange = bounds[:2], bounds[2:]
+	}
+	rho := make([]float64, len(records)-1)
+	votes := gray16.NewGray16(image.Rect(0, 0, len(theta), len(rho)))
+	for y := range rho {
+		if rho[y], err = parse(y+1, 0); err != nil {
+			return nil, err
+		}
+		for x := range theta {
+			v, err := parse(y+1, x+1)
+			if err != nil {
+				return nil, err
+			}
+			votes.Pix[votes.PixOffset(x, y)] = saturate(math.Floor(v + 0.5))
+		}
+	}
+	votes.UpdateRange()
+	a := &Accumulator{Gray16: votes}
+	if err := a.setAxes(rho, theta, rhoRange, thetaRange); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,192 @@
+package hough
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/edge"
+)
+
+// maxFastSize is the largest image side, after rounding up to a power of
+// two, that FastTransform accepts. It bounds the memory used, which is
+// 20*size*size bytes for the weights of the pixels and the two buffers of
+// the transform, besides the accumulator.
+const maxFastSize = 1 << 12
+
+// FastTransform computes the hough transform of the input with the fast
+// hough transform of Brady and Vuillemin, which takes O(n² log n) time for an
+// n by n image rather than the O(n³) of Transform.
+//
+// Lines are approximated by dyadic digital lines built recursively from the
+// two halves of the image, one half shifted relative to the other. This is
+// done for four orientations of the image, covering lines that are mostly
+// horizontal and mostly vertical with slopes of either sign. The score of
+// each dyadic line is the sum of the weights of the pixels it passes through
+// and is written, by maximum, into the bucket of the accumulator nearest its
+// angle and distance, so that the result can be used in place of that of
+// Transform. The dyadic lines deviate from straight lines by up to log2(n)/2
+// pixels, so the peaks are wider and the angles less precise than those of
+// Transform.
+//
+// All options of Transform are honoured except Spread, progress is reported
+// after each orientation.
+func FastTransform(input image.Image, opts ...Option) (*Accumulator, error) {
+	if input == nil {
+		return nil, errors.New("hough: nil input image")
+	}
+	t, err := newTransform(input.Bounds(), opts)
+	if err != nil {
+		return nil, err
+	}
+	b := input.Bounds()
+	n := 1
+	for n < b.Dx() || n < b.Dy() {
+		n *= 2
+	}
+	if n > maxFastSize {
+		return nil, fmt.Errorf("hough: input of %dx%d is too large for the fast transform", b.Dx(), b.Dy())
+	}
+
+	// Rasterise the weights of the voting pixels into an n by n image.
+	c := &t.config
+	at := edge.RGBA(input)
+	weights := make([]float32, n*n)
+	roi := t.roi.bounds
+	for y := roi.Min.Y; y < roi.Max.Y; y++ {
+		for x := roi.Min.X; x < roi.Max.X; x++ {
+			if !t.roi.contains(x, y) {
+				continue
+			}
+			r, g, bl, a := at(x, y)
+			if !c.predicate(r, g, bl, a) {
+				continue
+			}
+			if w := c.weigher(r, g, bl, a); w > 0 {
+				weights[(y-b.Min.Y)*n+(x-b.Min.X)] = float32(w)
+			}
+		}
+	}
+
+	src := make([]float32, 2*n*n)
+	dst := make([]float32, 2*n*n)
+	for q := 0; q < 4; q++ {
+		// Orient the image so that the lines of this quadrant are mostly
+		// horizontal and descend from left to right, the top n rows are left
+		// empty for the lines that enter through the top of the image.
+		for i := range src[:n*n] {
+			src[i] = 0
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				u, v := orient(q, n, float64(x), float64(y))
+				src[(n+y)*n+x] = weights[int(v)*n+int(u)]
+			}
+		}
+		h := fht(src, dst, n)
+		t.fastVotes(h, q, n, b.Min)
+		if c.progress != nil {
+			c.progress(float64(q+1) / 4)
+		}
+	}
+	return t.accumulator(), nil
+}
+
+// orient maps the point (x, y) of the image oriented for quadrant q to the
+// point (u, v) of the unoriented n by n image.
+func orient(q, n int, x, y float64) (u, v float64) {
+	last := float64(n - 1)
+	switch q {
+	case 1:
+		// Flipped vertically, for lines that ascend.
+		return x, last - y
+	case 2:
+		// Transposed, for mostly vertical lines.
+		return y, x
+	case 3:
+		// Transposed and flipped.
+		return last - y, x
+	}
+	return x, y
+}
+
+// fht computes the fast hough transform of the 2n rows by n columns image
+// src, using dst as scratch space, both are overwritten. In the returned
+// slice the entry at t*n+s is the sum along the dyadic line that runs from
+// (0, t) to (n-1, t+s).
+func fht(src, dst []float32, n int) []float32 {
+	rows := 2 * n
+	// Initially each column is a strip of width one holding the single line
+	// with no shift, so src already holds strip x at x. The layout used for
+	// a strip of width w starting at column j*w is [j][t][s], the layout of
+	// the final single strip of width n is therefore [t][s].
+	for w := 1; w < n; w *= 2 {
+		for j := 0; j < n/(2*w); j++ {
+			for t := 0; t < rows; t++ {
+				for s := 0; s < 2*w; s++ {
+					half := s / 2
+					shift := s - half
+					v := strip(src, n, w, 2*j, t, half)
+					if t+shift < rows {
+						v += strip(src, n, w, 2*j+1, t+shift, half)
+					}
+					dst[j*rows*2*w+t*2*w+s] = v
+				}
+			}
+		}
+		src, dst = dst, src
+	}
+	return src
+}
+
+// strip returns the entry (t, s) of strip j of width w, the initial strips
+// of width one are stored as the columns of the image.
+func strip(a []float32, n, w, j, t, s int) float32 {
+	if w == 1 {
+		return a[t*n+j]
+	}
+	return a[j*2*n*w+t*w+s]
+}
+
+// fastVotes writes the scores of the dyadic lines of quadrant q into the
+// votes, keeping the highest score that falls in each bucket.
+func (t *transform) fastVotes(h []float32, q, n int, min image.Point) {
+	acc := t.acc
+	halfTurn := math.Pi
+	for row := 0; row < 2*n; row++ {
+		for s := 0; s < n; s++ {
+			score := h[row*n+s]
+			if score == 0 {
+				continue
+			}
+			// The ends of the line in the original image coordinates.
+			x1, y1 := orient(q, n, 0, float64(row-n))
+			x2, y2 := orient(q, n, float64(n-1), float64(row-n+s))
+			x1 += float64(min.X)
+			y1 += float64(min.Y)
+			x2 += float64(min.X)
+			y2 += float64(min.Y)
+			// The normal of the line and its distance from the origin.
+			angle := math.Atan2(x2-x1, -(y2 - y1))
+			distance := (x1-acc.Origin.X)*math.Cos(angle) + (y1-acc.Origin.Y)*math.Sin(angle)
+			for angle < acc.MinAngle {
+				angle += halfTurn
+				distance = -distance
+			}
+			for angle >= acc.MinAngle+halfTurn {
+				angle -= halfTurn
+				distance = -distance
+			}
+			a := int(math.Floor(acc.AngleBin(angle) + 0.5))
+			d := int(math.Floor(acc.DistanceBin(distance) + 0.5))
+			if a < 0 || a >= t.votes.angles || d < 0 || d >= t.votes.distances {
+				continue
+			}
+			i := a*t.votes.distances + d
+			if score > t.votes.v[i] {
+				t.votes.v[i] = score
+			}
+		}
+	}
+}
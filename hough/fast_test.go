@@ -0,0 +1,65 @@
+package hough
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/point"
+)
+
+func TestFastTransform(t *testing.T) {
+	// Lines in each of the four quadrants of the fast transform.
+	cases := []struct {
+		angle, distance float64
+	}{
+		{math.Pi / 2, 10},
+		{math.Pi/2 + 0.4, -12},
+		{math.Pi/2 - 0.5, 5},
+		{0.2, 15},
+		{math.Pi - 0.3, -20},
+	}
+	for _, c := range cases {
+		im := image.NewGray(image.Rect(0, 0, 64, 64))
+		draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+		sin, cos := math.Sincos(c.angle)
+		// Plot the line x cos + y sin = distance about the centre.
+		for i := -64.0; i <= 64; i += 0.25 {
+			x := 32 + c.distance*cos - i*sin
+			y := 32 + c.distance*sin + i*cos
+			im.Set(int(math.Floor(x+0.5)), int(math.Floor(y+0.5)), color.Black)
+		}
+		acc, err := FastTransform(im, Size(128, 90))
+		if err != nil {
+			t.Fatal(err)
+		}
+		peak := strongestBucket(acc)
+		l := acc.LineAt(peak)
+		da := math.Abs(l.Angle - c.angle)
+		if da > 0.1 || math.Abs(l.Distance-c.distance) > 2 {
+			t.Errorf("Expecting line at angle %.3f distance %.3f got %+v", c.angle, c.distance, l)
+		}
+	}
+}
+
+func TestFastTransformTooLarge(t *testing.T) {
+	if _, err := FastTransform(image.NewGray(image.Rect(0, 0, maxFastSize+1, 1))); err == nil {
+		t.Error("Expecting error got nil")
+	}
+}
+
+func strongestBucket(acc *Accumulator) point.Point {
+	var max uint16
+	var at point.Point
+	for y := 0; y < acc.Rect.Dy(); y++ {
+		for x := 0; x < acc.Rect.Dx(); x++ {
+			if v := acc.Gray16At(x, y).Y; v > max {
+				max = v
+				at = point.Point{X: float64(x), Y: float64(y)}
+			}
+		}
+	}
+	return at
+}
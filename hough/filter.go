@@ -0,0 +1,156 @@
+package hough
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// butterflyKernel is the 3x3 filter of Leavers and Boyce, it responds to the
+// butterfly shaped peaks produced by lines, which are narrow in distance and
+// extended in angle, and gives zero for regions of constant value. The rows
+// are distance offsets and the columns angle offsets.
+var butterflyKernel = [3][3]float64{
+	{0, -2, 0},
+	{1, 2, 1},
+	{0, -2, 0},
+}
+
+// Butterfly returns a copy of the accumulator filtered to enhance the
+// butterfly shaped peaks of lines and suppress the spread of votes around
+// them. Negative responses are clipped to zero.
+func Butterfly(a *Accumulator) *Accumulator {
+	out := a.withPix()
+	b := a.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var sum float64
+			for j, row := range butterflyKernel {
+				for i, k := range row {
+					if k != 0 {
+						sum += k * float64(a.Gray16At(x+i-1, y+j-1).Y)
+					}
+				}
+			}
+			out.set(x, y, sum)
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+// SubtractBackground returns a copy of the accumulator with the mean of the
+// surrounding (2*radius+1)x(2*radius+1) window subtracted from each bucket,
+// this removes the broad background of votes cast by noise and by pixels that
+// do not lie on lines. Negative results are clipped to zero.
+func SubtractBackground(a *Accumulator, radius int) *Accumulator {
+	if radius < 1 {
+		radius = 1
+	}
+	out := a.withPix()
+	b := a.Rect
+	w, h := b.Dx(), b.Dy()
+	at := func(x, y int) float64 {
+		return float64(a.Gray16At(b.Min.X+x, b.Min.Y+y).Y)
+	}
+	// Box sums are computed separably with a sliding window, first along
+	// rows then down columns. The window for centre c covers [c-r, c+r].
+	rows := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		var sum float64
+		for x := 0; x < w+radius; x++ {
+			if x < w {
+				sum += at(x, y)
+			}
+			if x-2*radius-1 >= 0 {
+				sum -= at(x-2*radius-1, y)
+			}
+			if c := x - radius; c >= 0 {
+				rows[y*w+c] = sum
+			}
+		}
+	}
+	// span returns the number of buckets of the window around c that lie
+	// within [0, n).
+	span := func(c, n int) int {
+		lo, hi := c-radius, c+radius
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > n-1 {
+			hi = n - 1
+		}
+		return hi - lo + 1
+	}
+	for x := 0; x < w; x++ {
+		var sum float64
+		for y := 0; y < h+radius; y++ {
+			if y < h {
+				sum += rows[y*w+x]
+			}
+			if y-2*radius-1 >= 0 {
+				sum -= rows[(y-2*radius-1)*w+x]
+			}
+			if c := y - radius; c >= 0 {
+				mean := sum / float64(span(x, w)*span(c, h))
+				out.set(b.Min.X+x, b.Min.Y+c, at(x, c)-mean)
+			}
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+// NormaliseLength returns a copy of the accumulator with each bucket divided
+// by the length of the part of its line that crosses the input image. Long
+// lines, such as diagonals, can collect many more votes than short lines near
+// the corners of the image; normalising by length lets both be detected by
+// the same threshold. Buckets whose lines cross less than minLength pixels of
+// the input are set to zero since they are dominated by noise. The result is
+// scaled by the length of the input diagonal so that a diagonal keeps its
+// vote count.
+func NormaliseLength(a *Accumulator, minLength float64) *Accumulator {
+	out := a.withPix()
+	if minLength < 1 {
+		minLength = 1
+	}
+	b := a.Rect
+	longest := math.Hypot(float64(a.Input.Dx()), float64(a.Input.Dy()))
+	for x := b.Min.X; x < b.Max.X; x++ {
+		angle := a.Angle(float64(x - b.Min.X))
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			length := a.lineLength(angle, a.Distance(float64(y-b.Min.Y)))
+			if length < minLength {
+				out.set(x, y, 0)
+				continue
+			}
+			out.set(x, y, float64(a.Gray16At(x, y).Y)*longest/length)
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+// lineLength returns the length of the part of the line with the given angle
+// and distance from Origin that lies within Input.
+func (a *Accumulator) lineLength(angle, distance float64) float64 {
+	from, to, ok := a.Ends(Line{Angle: angle, Distance: distance})
+	if !ok {
+		return 0
+	}
+	return math.Hypot(to.X-from.X, to.Y-from.Y)
+}
+
+// withPix returns an accumulator sharing the axes of a with a new zeroed
+// image of the same bounds.
+func (a *Accumulator) withPix() *Accumulator {
+	out := *a
+	out.Gray16 = gray16.NewGray16(a.Rect)
+	return &out
+}
+
+// set sets the bucket at (x, y) to v, clipped to the range of a uint16.
+func (a *Accumulator) set(x, y int, v float64) {
+	a.SetGray16(x, y, color.Gray16{saturate(v + 0.5)})
+}
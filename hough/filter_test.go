@@ -0,0 +1,57 @@
+package hough
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/point"
+)
+
+func constantAccumulator(v uint16) *Accumulator {
+	acc := newAccumulator(image.Rect(0, 0, 40, 20), config{distances: 30, angles: 20, maxAngle: math.Pi, origin: point.Point{X: 20, Y: 10}})
+	for i := range acc.Pix {
+		acc.Pix[i] = v
+	}
+	acc.MaxVal = v
+	return acc
+}
+
+func TestFiltersRemoveConstantBackground(t *testing.T) {
+	acc := constantAccumulator(1000)
+	filtered := map[string]*Accumulator{
+		"butterfly":  Butterfly(acc),
+		"background": SubtractBackground(acc, 3),
+	}
+	for name, f := range filtered {
+		if f.Rect != acc.Rect || f.MaxDistance != acc.MaxDistance {
+			t.Errorf("%s: expecting axes of the input to be kept", name)
+		}
+		// Butterfly sees zeros beyond the edges so only check the interior.
+		for y := 1; y < f.Rect.Dy()-1; y++ {
+			for x := 1; x < f.Rect.Dx()-1; x++ {
+				if v := f.Gray16At(x, y).Y; v != 0 {
+					t.Fatalf("%s: expecting 0 at (%d, %d) got %d", name, x, y, v)
+				}
+			}
+		}
+	}
+}
+
+func TestLineLength(t *testing.T) {
+	acc := constantAccumulator(0)
+	cases := []struct {
+		angle, distance, length float64
+	}{
+		{0, 0, 20},
+		{math.Pi / 2, 0, 40},
+		{math.Pi / 2, 5, 40},
+		{math.Pi / 2, 11, 0},
+		{math.Pi / 4, 0, math.Sqrt2 * 20},
+	}
+	for _, c := range cases {
+		if l := acc.lineLength(c.angle, c.distance); math.Abs(l-c.length) > 1e-9 {
+			t.Errorf("angle %.3f distance %.3f: expecting length %.3f got %.3f", c.angle, c.distance, c.length, l)
+		}
+	}
+}
@@ -2,9 +2,12 @@
 package hough
 
 import (
+	"context"
+	"errors"
 	"image"
 	"math"
 
+	"github.com/piersy/hough-go/edge"
 	"github.com/piersy/hough-go/gray16"
 	"github.com/piersy/hough-go/norm"
 )
@@ -14,125 +17,211 @@ import (
 // line distance from centre of the input and the x axis represents the angle
 // of the line. Only black pixels are considered as contributing to the hough
 // transform.
+//
+// Hough is a thin wrapper around Transform, it panics if the transform cannot
+// be performed.
 func Hough(input image.Image, accDistance, accAngle int) *gray16.Gray16 {
-	width := input.Bounds().Dx()
-	height := input.Bounds().Dy()
-	midX := float64(width) / 2
-	midY := float64(height) / 2
-	// Precalculate angles for sin and cos
-	sinAngles := make([]float64, accDistance)
-	cosAngles := make([]float64, accDistance)
-	// Converts our accumulator angle buckets into appropriate radian values between 0 and Pi
-	angleN := norm.NewNormaliser(0, float64(accAngle), 0, math.Pi)
-	for t := 0; t < accAngle; t++ {
-		a := angleN.Normalise(float64(t))
-		sinAngles[t] = math.Sin(a)
-		cosAngles[t] = math.Cos(a)
+	acc, err := Transform(input, Size(accDistance, accAngle))
+	if err != nil {
+		panic(err)
 	}
+	return acc.Gray16
+}
 
-	// The max distance from centre, used for normalising the distance from the
-	// source image to the size of the accumulator.
-	maxDistance := math.Sqrt(float64(width*width+height*height)) / 2
-	distN := norm.NewNormaliser(-maxDistance, maxDistance, 0, float64(accDistance))
+// Transform returns the hough transform of the input image configured by the
+// given options. The options are validated before any work is done and an
+// error is returned if they do not describe a transform that can be
+// performed.
+func Transform(input image.Image, opts ...Option) (*Accumulator, error) {
+	return TransformContext(context.Background(), input, opts...)
+}
 
-	at := getRgba(input)
-	acc := gray16.NewGray16(image.Rect(0, 0, accDistance, accAngle))
-	stride := acc.Stride
-	pix := acc.Pix
-	var maxVal uint16
+// TransformContext is like Transform but checks ctx for cancellation before
+// each row of the input, if ctx is done the transform is abandoned and
+// ctx.Err() is returned.
+func TransformContext(ctx context.Context, input image.Image, opts ...Option) (*Accumulator, error) {
+	if input == nil {
+		return nil, errors.New("hough: nil input image")
+	}
+	t, err := newTransform(input.Bounds(), opts)
+	if err != nil {
+		return nil, err
+	}
+	t.tables()
+	at := edge.RGBA(input)
+	c := &t.config
+	roi := t.roi.bounds
+	batch := make([]edge.Point, 0, voteBatch)
 
-	// Iterate each pixel in the source
-	for x := 0; x < width; x++ {
-		px := float64(x) - midX
-		for y := 0; y < height; y++ {
-			py := float64(y) - midY
+	// Iterate each pixel in the region of interest row by row, collecting
+	// the pixels that vote into a batch of edges. Once enough have been
+	// collected they vote, angle by angle.
+	for y := roi.Min.Y; y < roi.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := roi.Min.X; x < roi.Max.X; x++ {
+			if !t.roi.contains(x, y) {
+				continue
+			}
+			r, g, b, a := at(x, y)
+			if !c.predicate(r, g, b, a) {
+				continue
+			}
+			batch = append(batch, edge.Point{X: x, Y: y, Weight: c.weigher(r, g, b, a)})
+		}
+		if len(batch) >= voteBatch || y == roi.Max.Y-1 {
+			t.vote(batch)
+			batch = batch[:0]
+			if c.progress != nil {
+				c.progress(float64(y-roi.Min.Y+1) / float64(roi.Dy()))
+			}
+		}
+	}
+	// An empty region has no rows to report, the transform is still done.
+	if c.progress != nil && roi.Empty() {
+		c.progress(1)
+	}
+	return t.accumulator(), nil
+}
 
-			// check black pixel
-			r, g, b, _ := at(x, y)
-			if r&g&b == 0 {
-				// For all angles represented in the accumulator, calculate
-				// perpendicular distance to the center of the input for a line
-				// through (x, y) at each angle and plot (dist, angle) in the
-				// accumulator.  Subsequent pixels that form a line of angle t
-				// with this pixel will share the same perpendicular distance
-				// at angle t and hence the point (d(t), t) will conicide for
-				// all pixels along the line.
-				for t := 0; t < accAngle; t++ {
-					//Get normal distance - can be negative
-					distance := px*cosAngles[t] + py*sinAngles[t]
-					// normalize distance into accumulator range.
-					// Accumulator range cannot benegative
-					dist := distN.Normalise(distance)
-					// The distance is likely to fall between two of our
-					// accumulator buckets so we divide the score
-					// appropriately between the buckets.
-					intDist := int(dist)
-					floatingPointPart := dist - float64(intDist)
-					//find different components of the score
-					further := 10.0 * floatingPointPart
-					nearer := 10.0 * (1.0 - floatingPointPart)
-					// Update the further pixel
-					pixel := (intDist+1)*stride + t
-					if pixel < len(pix) {
-						increment(uint16(further), &pix[pixel], &maxVal)
-					}
-					// Update the nearer pixel
-					pixel = intDist*stride + t
-					if pixel < len(pix) {
-						increment(uint16(nearer), &pix[pixel], &maxVal)
-					}
-				}
+// TransformEdges performs the hough transform of the points of an edge list
+// rather than an image. The origin defaults to the centre of the bounds of
+// the list and each point votes with its weight, the Votes and Weights
+// options are ignored. ctx is checked for cancellation and progress is
+// reported as batches of points are processed.
+func TransformEdges(ctx context.Context, edges *edge.List, opts ...Option) (*Accumulator, error) {
+	if edges == nil {
+		return nil, errors.New("hough: nil edge list")
+	}
+	t, err := newTransform(edges.Bounds, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.tables()
+	roi := t.roi.bounds
+	batch := make([]edge.Point, 0, voteBatch)
+	for i, p := range edges.Points {
+		if image.Pt(p.X, p.Y).In(roi) && t.roi.contains(p.X, p.Y) {
+			batch = append(batch, p)
+		}
+		if len(batch) >= voteBatch || i == len(edges.Points)-1 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			t.vote(batch)
+			batch = batch[:0]
+			if t.config.progress != nil {
+				t.config.progress(float64(i+1) / float64(len(edges.Points)))
 			}
 		}
 	}
-	// Set the max val on the acc so that it can be normalised correctly
-	acc.MaxVal = maxVal
-	return acc
+	if t.config.progress != nil && len(edges.Points) == 0 {
+		t.config.progress(1)
+	}
+	return t.accumulator(), nil
+}
+
+// transform holds the state of a transform in progress.
+type transform struct {
+	config config
+	acc    *Accumulator
+	roi    region
+	votes  *votes
+	// xs and ys hold the contribution of each column and row of the region
+	// to the normalised distance of lines through its pixels, the distance
+	// in buckets of the line at angle t through (x, y) is xs[t][x]+ys[t][y].
+	// They are filled in by tables.
+	xs, ys []float64
 }
 
-func increment(inc uint16, initial, max *uint16) {
-	result := *initial + inc
-	if result > *max {
-		*max = result
+// newTransform validates the options and prepares a transform of an input
+// with the given bounds.
+func newTransform(bounds image.Rectangle, opts []Option) (*transform, error) {
+	c := defaultConfig()
+	for _, o := range opts {
+		o(&c)
+	}
+	if bounds.Empty() {
+		return nil, errors.New("hough: empty input image")
+	}
+	if !c.originSet {
+		c.origin.X = float64(bounds.Min.X) + float64(bounds.Dx())/2
+		c.origin.Y = float64(bounds.Min.Y) + float64(bounds.Dy())/2
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	acc := newAccumulator(bounds, c)
+	// Precalculate angles for sin and cos
+	sinAngles := make([]float64, c.angles)
+	cosAngles := make([]float64, c.angles)
+	// Converts our accumulator angle buckets into appropriate radian values
+	for t := 0; t < c.angles; t++ {
+		a := acc.Angle(float64(t))
+		sinAngles[t] = math.Sin(a)
+		cosAngles[t] = math.Cos(a)
 	}
-	//Overflow simply hard limit at max
-	if result < *initial {
-		*max = math.MaxUint16
-		result = math.MaxUint16
+	roi := c.region(bounds)
+	if c.restrict && !roi.bounds.Empty() {
+		acc.MinDistance, acc.MaxDistance = roi.distanceRange(c.origin, sinAngles, cosAngles)
 	}
-	*initial = result
+	votes := newVotes(c.distances, c.angles)
+	// Votes spread beyond either end of a half turn of angles wrap to the
+	// other end with the distance negated, the distance D(x) of the
+	// fractional distance bucket x negates to D(negate-x).
+	votes.wrap = math.Abs(c.maxAngle-c.minAngle-math.Pi) < 1e-9
+	votes.negate = -2 * acc.MinDistance * float64(c.distances) / (acc.MaxDistance - acc.MinDistance)
+	return &transform{
+		config: c,
+		acc:    acc,
+		roi:    roi,
+		votes:  votes,
+	}, nil
 }
 
-// getAt reruns the At method defined on the underlying struct implementing
-// image.Image, if the image type is unknown then this function panics.
-// This performs about 25% better than calling at on an interface, I will accept it for now.
-// best performance is achieved by calling the types at method in the main loop of hough
-// but that would mean writing the algorithm once for each image type.
-func getRgba(i image.Image) func(int, int) (uint32, uint32, uint32, uint32) {
-	switch t := i.(type) {
-	case *image.Alpha:
-		return nil
-	case *image.Alpha16:
-		return nil
-	case *image.Gray:
-		return nil
-	case *image.Gray16:
-		return nil
-	case *image.NRGBA:
-		return func(x, y int) (uint32, uint32, uint32, uint32) {
-			return t.NRGBAAt(x, y).RGBA()
+// tables fills in xs and ys, which are needed to vote but not by the fast
+// transform.
+func (t *transform) tables() {
+	c, acc, roi := &t.config, t.acc, t.roi
+	distN := norm.NewNormaliser(acc.MinDistance, acc.MaxDistance, 0, float64(c.distances))
+	w, h := roi.bounds.Dx(), roi.bounds.Dy()
+	t.xs = make([]float64, c.angles*w)
+	t.ys = make([]float64, c.angles*h)
+	for a := 0; a < c.angles; a++ {
+		angle := acc.Angle(float64(a))
+		sin, cos := math.Sin(angle), math.Cos(angle)
+		for x := 0; x < w; x++ {
+			t.xs[a*w+x] = distN.Normalise((float64(roi.bounds.Min.X+x) - c.origin.X) * cos)
+		}
+		for y := 0; y < h; y++ {
+			t.ys[a*h+y] = distN.Normalise((float64(roi.bounds.Min.Y+y)-c.origin.Y)*sin) - distN.Normalise(0)
 		}
-	case *image.NRGBA64:
-		return nil
-	case *image.Paletted:
-		return nil
-	case *image.RGBA:
-		return func(x, y int) (uint32, uint32, uint32, uint32) {
-			return t.RGBAAt(x, y).RGBA()
+	}
+}
+
+// accumulator copies the votes into the accumulator, updating its range of
+// values so that it can be normalised correctly, and returns it.
+func (t *transform) accumulator() *Accumulator {
+	acc := t.acc
+	for a := 0; a < t.votes.angles; a++ {
+		for d, vote := range t.votes.v[a*t.votes.distances : (a+1)*t.votes.distances] {
+			acc.Pix[d*acc.Stride+a] = saturate(float64(vote) + 0.5)
 		}
-	case *image.RGBA64:
-		return nil
-	default:
-		panic("unrecognised image type")
 	}
+	acc.UpdateRange()
+	return acc
+}
+
+// saturate converts a vote into a uint16, votes too large to be represented
+// are hard limited to the max uint16.
+func saturate(v float64) uint16 {
+	if v >= math.MaxUint16 {
+		return math.MaxUint16
+	}
+	if v < 0 {
+		return 0
+	}
+	return uint16(v)
 }
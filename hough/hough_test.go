@@ -17,8 +17,9 @@ var (
 	in = flag.String("in", "", "input image")
 )
 
-func init() {
+func TestMain(m *testing.M) {
 	flag.Parse()
+	os.Exit(m.Run())
 }
 
 func BenchmarkHough(b *testing.B) {
@@ -56,6 +57,9 @@ func BenchmarkNormaliseGray16Method(b *testing.B) {
 }
 
 func getImage(b *testing.B) image.Image {
+	if *in == "" {
+		b.Skip("no input image, set one with -in")
+	}
 	f, err := os.Open(*in)
 	defer f.Close()
 	if err != nil {
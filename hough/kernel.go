@@ -0,0 +1,193 @@
+package hough
+
+import (
+	"fmt"
+	"math"
+)
+
+// Kernel determines how the vote for a line is spread over the accumulator
+// buckets around the line's exact distance and angle. Nearest, Linear and
+// Gaussian are provided, other spreads can be given to Spread by
+// implementing Kernel.
+type Kernel interface {
+	// Spread calls add for each bucket the vote for a line at the fractional
+	// distance bucket dist is spread over. add is given the offset of the
+	// bucket's angle from the angle bucket of the line, the bucket's
+	// distance bucket and the share of the vote it receives, the shares
+	// should sum to 1. Bucket d is centred on the fractional distance d.
+	Spread(dist float64, add func(angle, distance int, share float64))
+}
+
+var (
+	// Nearest gives the whole vote to the nearest distance bucket.
+	Nearest Kernel = nearest{}
+	// Linear divides the vote between the two nearest distance buckets in
+	// proportion to how close the line lies to each. It is the default.
+	Linear Kernel = linear{}
+)
+
+// votes holds the votes of a transform before they are written into the
+// accumulator, they are held as floats so that small fractions of votes
+// spread by a Kernel are not lost. Unlike the accumulator the votes for each
+// angle are held contiguously.
+type votes struct {
+	v                 []float32
+	distances, angles int
+	// wrap is set when the angles span a half turn, the buckets beyond
+	// either end of the angles are then those at the other end with the
+	// distance negated, negate-x being the negation of the fractional
+	// distance x.
+	wrap   bool
+	negate float64
+
+	// The vote being spread, see spread.
+	t                int
+	vote             float64
+	wrapped, outside bool
+	addShare         func(angle, distance int, share float64)
+}
+
+func newVotes(distances, angles int) *votes {
+	v := &votes{
+		v:         make([]float32, distances*angles),
+		distances: distances,
+		angles:    angles,
+	}
+	// Bind the method once rather than for every vote.
+	v.addShare = v.share
+	return v
+}
+
+// add adds vote to the bucket (d, t), buckets outside the accumulator are
+// ignored.
+func (v *votes) add(t, d int, vote float64) {
+	if t < 0 || t >= v.angles || d < 0 || d >= v.distances {
+		return
+	}
+	v.v[t*v.distances+d] += float32(vote)
+}
+
+// spread spreads vote for the line at the fractional distance bucket dist in
+// angle bucket t with k. If the angles wrap, the shares that k spreads beyond
+// either end of the angles are spread again from the same line expressed at
+// the other end, with its distance negated.
+func (v *votes) spread(k Kernel, t int, dist, vote float64) {
+	v.t, v.vote, v.wrapped, v.outside = t, vote, false, false
+	k.Spread(dist, v.addShare)
+	if v.outside && v.wrap {
+		v.wrapped = true
+		k.Spread(v.negate-dist, v.addShare)
+	}
+}
+
+// share adds a share of the vote being spread to the bucket at the angle
+// offset from its line, either within the angles or, when the vote is being
+// spread again from the other end, beyond them.
+func (v *votes) share(angle, d int, share float64) {
+	t := v.t + angle
+	inside := t >= 0 && t < v.angles
+	switch {
+	case !v.wrapped && !inside:
+		v.outside = true
+	case !v.wrapped:
+		v.add(t, d, v.vote*share)
+	case t < 0:
+		v.add(t+v.angles, d, v.vote*share)
+	case t >= v.angles:
+		v.add(t-v.angles, d, v.vote*share)
+	}
+}
+
+type nearest struct{}
+
+func (nearest) Spread(dist float64, add func(angle, distance int, share float64)) {
+	add(0, int(math.Floor(dist+0.5)), 1)
+}
+
+type linear struct{}
+
+func (linear) Spread(dist float64, add func(angle, distance int, share float64)) {
+	// The distance is likely to fall between two of our accumulator buckets
+	// so we divide the score appropriately between the buckets.
+	intDist := math.Floor(dist)
+	floatingPointPart := dist - intDist
+	// Update the further pixel
+	add(0, int(intDist)+1, floatingPointPart)
+	// Update the nearer pixel
+	add(0, int(intDist), 1.0-floatingPointPart)
+}
+
+// Gaussian returns a Kernel that spreads the vote over the surrounding
+// buckets following a two dimensional gaussian. The standard deviations are
+// given in buckets, the spread is truncated at three standard deviations. A
+// standard deviation of zero disables spreading along that axis. When the
+// accumulator covers a half turn of angles, the spread beyond either end
+// wraps to the other end with the distance negated, as it does for every
+// Kernel.
+func Gaussian(sigmaDistance, sigmaAngle float64) Kernel {
+	g := gaussian{sigmaDistance: sigmaDistance, sigmaAngle: sigmaAngle}
+	if g.validate() != nil {
+		return g
+	}
+	// The angle weights do not depend on the vote so precompute them.
+	g.angleRadius = int(math.Ceil(3 * sigmaAngle))
+	g.angleWeights = make([]float64, 2*g.angleRadius+1)
+	var total float64
+	for i := range g.angleWeights {
+		w := 1.0
+		if sigmaAngle > 0 {
+			o := float64(i - g.angleRadius)
+			w = math.Exp(-o * o / (2 * sigmaAngle * sigmaAngle))
+		}
+		g.angleWeights[i] = w
+		total += w
+	}
+	for i := range g.angleWeights {
+		g.angleWeights[i] /= total
+	}
+	g.distanceRadius = int(math.Ceil(3 * sigmaDistance))
+	return g
+}
+
+type gaussian struct {
+	sigmaDistance, sigmaAngle   float64
+	distanceRadius, angleRadius int
+	angleWeights                []float64
+}
+
+func (g gaussian) Spread(dist float64, add func(angle, distance int, share float64)) {
+	if g.sigmaDistance == 0 {
+		d := int(math.Floor(dist + 0.5))
+		for i, w := range g.angleWeights {
+			add(i-g.angleRadius, d, w)
+		}
+		return
+	}
+	centre := int(math.Floor(dist + 0.5))
+	var weights [64]float64
+	ws := weights[:0]
+	var total float64
+	for d := centre - g.distanceRadius; d <= centre+g.distanceRadius; d++ {
+		o := float64(d) - dist
+		w := math.Exp(-o * o / (2 * g.sigmaDistance * g.sigmaDistance))
+		ws = append(ws, w)
+		total += w
+	}
+	for i, aw := range g.angleWeights {
+		for j, dw := range ws {
+			add(i-g.angleRadius, centre-g.distanceRadius+j, aw*dw/total)
+		}
+	}
+}
+
+func (g gaussian) validate() error {
+	if !finite(g.sigmaDistance) || !finite(g.sigmaAngle) || g.sigmaDistance < 0 || g.sigmaAngle < 0 {
+		return fmt.Errorf("hough: invalid gaussian kernel standard deviations %g, %g", g.sigmaDistance, g.sigmaAngle)
+	}
+	// Limit the spread so that a single vote does not touch an
+	// unreasonable number of buckets.
+	if g.sigmaDistance > 10 || g.sigmaAngle > 10 {
+		return fmt.Errorf("hough: gaussian kernel standard deviations %g, %g exceed the limit of 10 buckets", g.sigmaDistance, g.sigmaAngle)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package hough
+
+import (
+	"math"
+	"sort"
+
+	"github.com/piersy/hough-go/point"
+)
+
+// Line is a line found in an accumulator.
+type Line struct {
+	// Angle is the angle in radians of the normal to the line and Distance
+	// is the perpendicular distance of the line from the accumulator origin.
+	Angle, Distance float64
+	// Votes is the score the line received in the accumulator.
+	Votes float64
+}
+
+// LineAt returns the line at the point p in accumulator coordinates, such as
+// the centre of a blob. Its votes are interpolated from the surrounding
+// buckets.
+func (a *Accumulator) LineAt(p point.Point) Line {
+	return Line{
+		Angle:    a.Angle(p.X),
+		Distance: a.Distance(p.Y),
+		Votes:    a.interpolate(p),
+	}
+}
+
+// Ends returns the points at which the line l crosses the bounds of Input,
+// ok is false if the line does not pass through Input.
+func (a *Accumulator) Ends(l Line) (from, to point.Point, ok bool) {
+	// The line passes through the point p at distance along its normal and
+	// runs in direction d. Clip the parameter of p + s*d to the input.
+	sin, cos := math.Sincos(l.Angle)
+	px := a.Origin.X + l.Distance*cos
+	py := a.Origin.Y + l.Distance*sin
+	dx, dy := -sin, cos
+	lo, hi := math.Inf(-1), math.Inf(1)
+	clip := func(p, d, min, max float64) bool {
+		if math.Abs(d) < 1e-12 {
+			return p >= min && p <= max
+		}
+		s1, s2 := (min-p)/d, (max-p)/d
+		if s1 > s2 {
+			s1, s2 = s2, s1
+		}
+		lo = math.Max(lo, s1)
+		hi = math.Min(hi, s2)
+		return true
+	}
+	if !clip(px, dx, float64(a.Input.Min.X), float64(a.Input.Max.X)) ||
+		!clip(py, dy, float64(a.Input.Min.Y), float64(a.Input.Max.Y)) || hi <= lo {
+		return point.Point{}, point.Point{}, false
+	}
+	from = point.Point{X: px + lo*dx, Y: py + lo*dy}
+	to = point.Point{X: px + hi*dx, Y: py + hi*dy}
+	return from, to, true
+}
+
+// interpolate returns the bilinearly interpolated value of the accumulator
+// at p.
+func (a *Accumulator) interpolate(p point.Point) float64 {
+	x0, y0 := math.Floor(p.X), math.Floor(p.Y)
+	fx, fy := p.X-x0, p.Y-y0
+	at := func(x, y float64) float64 {
+		return float64(a.Gray16At(int(x), int(y)).Y)
+	}
+	top := at(x0, y0)*(1-fx) + at(x0+1, y0)*fx
+	bottom := at(x0, y0+1)*(1-fx) + at(x0+1, y0+1)*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// align returns l expressed with an angle within Pi/2 of ref. Lines with
+// angles a and a+Pi are the same line with the sign of the distance flipped,
+// so lines either side of the wrap at Pi can be compared once aligned.
+func (l Line) align(ref float64) Line {
+	for l.Angle-ref > math.Pi/2 {
+		l.Angle -= math.Pi
+		l.Distance = -l.Distance
+	}
+	for ref-l.Angle > math.Pi/2 {
+		l.Angle += math.Pi
+		l.Distance = -l.Distance
+	}
+	return l
+}
+
+// Merge clusters lines that lie within angleTol radians and distanceTol
+// pixels of each other and replaces each cluster with a single line. Lines
+// are clustered transitively, so a chain of close lines forms one cluster.
+// The merged line is the vote weighted mean of the cluster, expressed
+// relative to the angle of its strongest line and then brought back within
+// the angles of the cluster's lines, or failing that within [0, Pi), so that
+// it lies in the range of the accumulator the lines came from. It holds the
+// sum of the cluster's votes. The result is ordered by decreasing votes.
+func Merge(lines []Line, angleTol, distanceTol float64) []Line {
+	// Union find over the line indices.
+	parent := make([]int, len(lines))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range lines {
+		for j := i + 1; j < len(lines); j++ {
+			o := lines[j].align(lines[i].Angle)
+			if math.Abs(o.Angle-lines[i].Angle) <= angleTol && math.Abs(o.Distance-lines[i].Distance) <= distanceTol {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	clusters := make(map[int][]Line)
+	var roots []int
+	for i, l := range lines {
+		r := find(i)
+		if _, ok := clusters[r]; !ok {
+			roots = append(roots, r)
+		}
+		clusters[r] = append(clusters[r], l)
+	}
+
+	merged := make([]Line, 0, len(roots))
+	for _, r := range roots {
+		merged = append(merged, mergeCluster(clusters[r]))
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Votes > merged[j].Votes
+	})
+	return merged
+}
+
+// mergeCluster returns the vote weighted mean of the lines.
+func mergeCluster(lines []Line) Line {
+	strongest := lines[0]
+	lo, hi := lines[0].Angle, lines[0].Angle
+	var total float64
+	for _, l := range lines {
+		if l.Votes > strongest.Votes {
+			strongest = l
+		}
+		lo, hi = math.Min(lo, l.Angle), math.Max(hi, l.Angle)
+		total += l.Votes
+	}
+	var angle, distance float64
+	for _, l := range lines {
+		// Weigh lines equally if there are no votes to go by.
+		w := 1 / float64(len(lines))
+		if total > 0 {
+			w = l.Votes / total
+		}
+		l = l.align(strongest.Angle)
+		angle += w * l.Angle
+		distance += w * l.Distance
+	}
+	// The mean of lines either side of the wrap at Pi may lie outside their
+	// angles, it is brought back by a half turn.
+	l := Line{Angle: angle, Distance: distance, Votes: total}.align(lo + math.Pi/2)
+	if l.Angle > hi && l.Angle >= math.Pi {
+		l.Angle -= math.Pi
+		l.Distance = -l.Distance
+	}
+	return l
+}
@@ -0,0 +1,77 @@
+package hough
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/point"
+)
+
+func TestMerge(t *testing.T) {
+	lines := []Line{
+		{Angle: 1, Distance: 10, Votes: 30},
+		{Angle: 1.01, Distance: 12, Votes: 10},
+		// Either side of the wrap at Pi, the same line as the next.
+		{Angle: 0.005, Distance: -50, Votes: 20},
+		{Angle: math.Pi - 0.005, Distance: 50, Votes: 20},
+		{Angle: 2, Distance: 10, Votes: 5},
+	}
+	merged := Merge(lines, 0.02, 3)
+	if len(merged) != 3 {
+		t.Fatalf("Expecting 3 lines got %d: %+v", len(merged), merged)
+	}
+	expected := []Line{
+		{Angle: 1.0025, Distance: 10.5, Votes: 40},
+		{Angle: 0, Distance: -50, Votes: 40},
+		{Angle: 2, Distance: 10, Votes: 5},
+	}
+	for i, e := range expected {
+		m := merged[i]
+		if math.Abs(m.Angle-e.Angle) > 1e-9 || math.Abs(m.Distance-e.Distance) > 1e-9 || m.Votes != e.Votes {
+			t.Errorf("Expecting line %d to be %+v got %+v", i, e, m)
+		}
+	}
+}
+
+func TestMergeWrap(t *testing.T) {
+	// Lines either side of the wrap whose mean falls outside [0, Pi) when
+	// taken relative to the strongest.
+	for _, c := range []struct {
+		lines    []Line
+		expected Line
+	}{
+		{
+			[]Line{{Angle: 0.002, Distance: -50, Votes: 30}, {Angle: math.Pi - 0.01, Distance: 50, Votes: 10}},
+			Line{Angle: math.Pi - 0.001, Distance: 50, Votes: 40},
+		},
+		{
+			[]Line{{Angle: math.Pi - 0.002, Distance: 50, Votes: 30}, {Angle: 0.01, Distance: -50, Votes: 10}},
+			Line{Angle: 0.001, Distance: -50, Votes: 40},
+		},
+	} {
+		merged := Merge(c.lines, 0.02, 3)
+		if len(merged) != 1 {
+			t.Fatalf("Expecting 1 line got %d: %+v", len(merged), merged)
+		}
+		m := merged[0]
+		if math.Abs(m.Angle-c.expected.Angle) > 1e-9 || math.Abs(m.Distance-c.expected.Distance) > 1e-9 || m.Votes != c.expected.Votes {
+			t.Errorf("Expecting %+v got %+v", c.expected, m)
+		}
+	}
+}
+
+func TestEnds(t *testing.T) {
+	a := &Accumulator{Origin: point.Point{X: 50, Y: 25}, Input: image.Rect(0, 0, 100, 50)}
+	// A horizontal line 10 pixels below the origin.
+	from, to, ok := a.Ends(Line{Angle: math.Pi / 2, Distance: 10})
+	if !ok {
+		t.Fatal("Expecting the line to cross the input")
+	}
+	if math.Abs(from.Y-35) > 1e-9 || math.Abs(to.Y-35) > 1e-9 || math.Abs(math.Abs(to.X-from.X)-100) > 1e-9 {
+		t.Errorf("Expecting ends at y 35 spanning the input got %+v %+v", from, to)
+	}
+	if _, _, ok := a.Ends(Line{Angle: 0, Distance: 60}); ok {
+		t.Error("Expecting a line outside the input to have no ends")
+	}
+}
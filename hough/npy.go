@@ -0,0 +1,498 @@
+package hough
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/point"
+)
+
+// DType is the element type the votes are written with.
+type DType int
+
+const (
+	// Uint16 writes the votes as they are held in the accumulator.
+	Uint16 DType = iota
+	// Uint32 writes the votes widened to 32 bits, for tools that would
+	// otherwise overflow summing them.
+	Uint32
+	// Float32 writes the votes as floating point.
+	Float32
+)
+
+// descr returns the NumPy type description of the little endian type.
+func (t DType) descr() (string, error) {
+	switch t {
+	case Uint16:
+		return "<u2", nil
+	case Uint32:
+		return "<u4", nil
+	case Float32:
+		return "<f4", nil
+	}
+	return "", fmt.Errorf("hough: unknown dtype %d", t)
+}
+
+// WriteNPY writes the votes of the accumulator to w in the NumPy .npy format
+// as an array of the given type with a row for each distance and a column
+// for each angle, so that votes[d, t] is the votes for the line at distance
+// Distance(d) and angle Angle(t). Use WriteNPZ to include the axes.
+func (a *Accumulator) WriteNPY(w io.Writer, t DType) error {
+	descr, err := t.descr()
+	if err != nil {
+		return err
+	}
+	return writeNPY(w, descr, []int{a.Rect.Dy(), a.Rect.Dx()}, a.votes())
+}
+
+// WriteNPZ writes the accumulator to w as a NumPy .npz archive, as produced
+// by numpy.savez, holding the arrays
+//
+//	votes        the votes as written by WriteNPY
+//	rho          the distance of each row, Distance(d)
+//	theta        the angle of each column in radians, Angle(t)
+//	rho_range    the range of distances as [MinDistance, MaxDistance]
+//	theta_range  the range of angles as [MinAngle, MaxAngle]
+//	origin       the Origin as [x, y]
+//	input        the Input bounds as [min x, min y, max x, max y]
+//
+// so that the accumulator can be inspected outside Go and read back with
+// ReadNPZ.
+func (a *Accumulator) WriteNPZ(w io.Writer, t DType) error {
+	descr, err := t.descr()
+	if err != nil {
+		return err
+	}
+	rho := make([]float64, a.Rect.Dy())
+	for d := range rho {
+		rho[d] = a.Distance(float64(d))
+	}
+	theta := make([]float64, a.Rect.Dx())
+	for i := range theta {
+		theta[i] = a.Angle(float64(i))
+	}
+	arrays := []struct {
+		name  string
+		descr string
+		shape []int
+		data  []float64
+	}{
+		{"votes", descr, []int{a.Rect.Dy(), a.Rect.Dx()}, a.votes()},
+		{"rho", "<f8", []int{len(rho)}, rho},
+		{"theta", "<f8", []int{len(theta)}, theta},
+		{"rho_range", "<f8", []int{2}, []float64{a.MinDistance, a.MaxDistance}},
+		{"theta_range", "<f8", []int{2}, []float64{a.MinAngle, a.MaxAngle}},
+		{"origin", "<f8", []int{2}, []float64{a.Origin.X, a.Origin.Y}},
+		{"input", "<i8", []int{4}, []float64{
+			float64(a.Input.Min.X), float64(a.Input.Min.Y), float64(a.Input.Max.X), float64(a.Input.Max.Y),
+		}},
+	}
+	z := zip.NewWriter(w)
+	for _, arr := range arrays {
+		f, err := z.CreateHeader(&zip.FileHeader{Name: arr.name + ".npy", Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := writeNPY(f, arr.descr, arr.shape, arr.data); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// ReadNPY reads a two dimensional array of votes in the NumPy .npy format
+// from r, such as one written by WriteNPY. Arrays of unsigned and signed
+// integers and floats of any size and byte order are accepted, values are
+// rounded and saturated to 16 bits.
+func ReadNPY(r io.Reader) (*gray16.Gray16, error) {
+	shape, data, err := readNPY(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("hough: votes have %d dimensions, 2 are needed", len(shape))
+	}
+	g := gray16.NewGray16(image.Rect(0, 0, shape[1], shape[0]))
+	for i, v := range data {
+		g.Pix[i] = saturate(math.Floor(v + 0.5))
+	}
+	g.UpdateRange()
+	return g, nil
+}
+
+// ReadNPZ reads an accumulator from the NumPy .npz archive r of the given
+// size, such as one written by WriteNPZ. The votes are read as by ReadNPY.
+// The range of each axis is read from its range array, the values of the axis
+// must be evenly spaced over it. Archives without the range arrays have the
+// range of each axis recovered from its values, of which there must then be
+// at least 2.
+func ReadNPZ(r io.ReaderAt, size int64) (*Accumulator, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("hough: reading npz: %v", err)
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		for _, f := range z.File {
+			if f.Name == name+".npy" {
+				return f.Open()
+			}
+		}
+		return nil, fmt.Errorf("hough: npz has no %s array", name)
+	}
+	has := func(name string) bool {
+		for _, f := range z.File {
+			if f.Name == name+".npy" {
+				return true
+			}
+		}
+		return false
+	}
+	read := func(name string, n int) ([]float64, error) {
+		f, err := open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		shape, data, err := readNPY(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(shape) != 1 || (n > 0 && shape[0] != n) {
+			return nil, fmt.Errorf("hough: %s has shape %v", name, shape)
+		}
+		return data, nil
+	}
+
+	f, err := open("votes")
+	if err != nil {
+		return nil, err
+	}
+	votes, err := ReadNPY(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	rho, err := read("rho", votes.Rect.Dy())
+	if err != nil {
+		return nil, err
+	}
+	theta, err := read("theta", votes.Rect.Dx())
+	if err != nil {
+		return nil, err
+	}
+	var rhoRange, thetaRange []float64
+	if has("rho_range") || has("theta_range") {
+		if rhoRange, err = read("rho_range", 2); err != nil {
+			return nil, err
+		}
+		if thetaRange, err = read("theta_range", 2); err != nil {
+			return nil, err
+		}
+	}
+	origin, err := read("origin", 2)
+	if err != nil {
+		return nil, err
+	}
+	input, err := read("input", 4)
+	if err != nil {
+		return nil, err
+	}
+	a := &Accumulator{
+		Gray16: votes,
+		Origin: point.Point{X: origin[0], Y: origin[1]},
+		Input:  image.Rect(int(input[0]), int(input[1]), int(input[2]), int(input[3])),
+	}
+	if err := a.setAxes(rho, theta, rhoRange, thetaRange); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// votes returns the votes of the accumulator row by row.
+func (a *Accumulator) votes() []float64 {
+	v := make([]float64, 0, a.Rect.Dx()*a.Rect.Dy())
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			v = append(v, float64(a.Pix[a.PixOffset(x, y)]))
+		}
+	}
+	return v
+}
+
+// setAxes sets the angle and distance ranges of the accumulator from the
+// angle of each column and the distance of each row, and from the range of
+// each axis as [min, max] where it is known.
+func (a *Accumulator) setAxes(rho, theta, rhoRange, thetaRange []float64) error {
+	var err error
+	if a.MinDistance, a.MaxDistance, err = axisRange(rho, rhoRange); err != nil {
+		return fmt.Errorf("hough: rho %v", err)
+	}
+	if a.MinAngle, a.MaxAngle, err = axisRange(theta, thetaRange); err != nil {
+		return fmt.Errorf("hough: theta %v", err)
+	}
+	return nil
+}
+
+// axisRange returns the half open range divided evenly into buckets starting
+// at the values. The range is given by bounds, as [min, max], if it is not nil
+// and otherwise recovered from the values, of which there must be at least 2.
+func axisRange(values, bounds []float64) (min, max float64, err error) {
+	n := len(values)
+	switch {
+	case bounds != nil:
+		min, max = bounds[0], bounds[1]
+		if !finite(min) || !finite(max) {
+			return 0, 0, fmt.Errorf("has an invalid range %v", bounds)
+		}
+		if n == 0 {
+			return 0, 0, errors.New("has no values")
+		}
+	case n < 2:
+		return 0, 0, fmt.Errorf("has %d values, at least 2 are needed", n)
+	default:
+		min = values[0]
+		max = min + (values[n-1]-min)/float64(n-1)*float64(n)
+	}
+	step := (max - min) / float64(n)
+	for i, v := range values {
+		if !finite(v) || math.Abs(v-(min+float64(i)*step)) > 1e-6*math.Max(1, math.Abs(step)) {
+			return 0, 0, errors.New("is not evenly spaced over its range")
+		}
+	}
+	if !(step > 0) {
+		return 0, 0, errors.New("is not increasing")
+	}
+	return min, max, nil
+}
+
+const npyMagic = "\x93NUMPY"
+
+// writeNPY writes the data, in row major order, as a version 1.0 .npy array
+// of the given type and shape.
+func writeNPY(w io.Writer, descr string, shape []int, data []float64) error {
+	dims := make([]string, len(shape))
+	for i, s := range shape {
+		dims[i] = strconv.Itoa(s)
+	}
+	shapeStr := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shapeStr)
+	// The header is padded with spaces and ends with a newline so that the
+	// data is aligned to 64 bytes.
+	prefix := len(npyMagic) + 2 + 2
+	pad := 64 - (prefix+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(npyMagic)
+	bw.Write([]byte{1, 0})
+	binary.Write(bw, binary.LittleEndian, uint16(len(header)))
+	bw.WriteString(header)
+	var buf [8]byte
+	for _, v := range data {
+		switch descr {
+		case "<u2":
+			binary.LittleEndian.PutUint16(buf[:], uint16(v))
+			bw.Write(buf[:2])
+		case "<u4":
+			binary.LittleEndian.PutUint32(buf[:], uint32(v))
+			bw.Write(buf[:4])
+		case "<f4":
+			binary.LittleEndian.PutUint32(buf[:], math.Float32bits(float32(v)))
+			bw.Write(buf[:4])
+		case "<f8":
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			bw.Write(buf[:8])
+		case "<i8":
+			binary.LittleEndian.PutUint64(buf[:], uint64(int64(v)))
+			bw.Write(buf[:8])
+		default:
+			return fmt.Errorf("hough: unsupported npy type %q", descr)
+		}
+	}
+	return bw.Flush()
+}
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// npyMaxHeader is the longest npy header read, the limit numpy itself applies
+// by default.
+const npyMaxHeader = 10000
+
+// npyChunk is the number of elements of an npy array read at a time.
+const npyChunk = 1 << 16
+
+// readNPY reads a .npy array of any version, returning its shape and its
+// data in row major order.
+func readNPY(r io.Reader) (shape []int, data []float64, err error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, nil, fmt.Errorf("hough: reading npy: %v", err)
+	}
+	if string(magic[:len(npyMagic)]) != npyMagic {
+		return nil, nil, errors.New("hough: not an npy array")
+	}
+	var headerLen int
+	switch magic[len(npyMagic)] {
+	case 1:
+		var l uint16
+		err = binary.Read(br, binary.LittleEndian, &l)
+		headerLen = int(l)
+	case 2, 3:
+		var l uint32
+		err = binary.Read(br, binary.LittleEndian, &l)
+		headerLen = int(l)
+	default:
+		return nil, nil, fmt.Errorf("hough: unsupported npy version %d", magic[len(npyMagic)])
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("hough: reading npy: %v", err)
+	}
+	if headerLen > npyMaxHeader {
+		return nil, nil, fmt.Errorf("hough: npy header of %d bytes is too long", headerLen)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, nil, fmt.Errorf("hough: reading npy header: %v", err)
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	fortran := npyFortran.FindSubmatch(header)
+	dims := npyShape.FindSubmatch(header)
+	if descr == nil || fortran == nil || dims == nil {
+		return nil, nil, fmt.Errorf("hough: invalid npy header %q", header)
+	}
+	n := 1
+	for _, s := range strings.Split(string(dims[1]), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 0 {
+			return nil, nil, fmt.Errorf("hough: invalid npy shape (%s)", dims[1])
+		}
+		if d > 0 && n > math.MaxInt32/d {
+			return nil, nil, fmt.Errorf("hough: npy shape (%s) is too large", dims[1])
+		}
+		shape = append(shape, d)
+		n *= d
+	}
+
+	order, kind, size, err := parseDescr(string(descr[1]))
+	if err != nil {
+		return nil, nil, err
+	}
+	// The data is read in chunks, rather than allocated up front from the
+	// shape, so that a truncated or hostile header cannot claim more memory
+	// than the data that is actually present.
+	raw := make([]byte, npyChunk*size)
+	for len(data) < n {
+		chunk := raw
+		if left := n - len(data); left < npyChunk {
+			chunk = raw[:left*size]
+		}
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, nil, fmt.Errorf("hough: reading npy data: %v", err)
+		}
+		for i := 0; i < len(chunk); i += size {
+			data = append(data, decode(order, kind, chunk[i:i+size]))
+		}
+	}
+	if string(fortran[1]) == "True" && len(shape) > 1 {
+		data = fromFortran(data, shape)
+	}
+	return shape, data, nil
+}
+
+// parseDescr parses a NumPy type description such as "<u2".
+func parseDescr(descr string) (order binary.ByteOrder, kind byte, size int, err error) {
+	if len(descr) < 3 {
+		return nil, 0, 0, fmt.Errorf("hough: unsupported npy type %q", descr)
+	}
+	switch descr[0] {
+	case '<', '|', '=':
+		order = binary.LittleEndian
+	case '>':
+		order = binary.BigEndian
+	default:
+		return nil, 0, 0, fmt.Errorf("hough: unsupported npy type %q", descr)
+	}
+	kind = descr[1]
+	size, _ = strconv.Atoi(descr[2:])
+	switch {
+	case (kind == 'u' || kind == 'i') && (size == 1 || size == 2 || size == 4 || size == 8):
+	case kind == 'f' && (size == 4 || size == 8):
+	default:
+		return nil, 0, 0, fmt.Errorf("hough: unsupported npy type %q", descr)
+	}
+	return order, kind, size, nil
+}
+
+// decode returns the value of the element b.
+func decode(order binary.ByteOrder, kind byte, b []byte) float64 {
+	var u uint64
+	switch len(b) {
+	case 1:
+		u = uint64(b[0])
+	case 2:
+		u = uint64(order.Uint16(b))
+	case 4:
+		u = uint64(order.Uint32(b))
+	case 8:
+		u = order.Uint64(b)
+	}
+	switch kind {
+	case 'i':
+		// Sign extend from the size of the element.
+		shift := 64 - 8*uint(len(b))
+		return float64(int64(u<<shift) >> shift)
+	case 'f':
+		if len(b) == 4 {
+			return float64(math.Float32frombits(uint32(u)))
+		}
+		return math.Float64frombits(u)
+	}
+	return float64(u)
+}
+
+// fromFortran reorders data of the given shape from column major to row
+// major order.
+func fromFortran(data []float64, shape []int) []float64 {
+	out := make([]float64, len(data))
+	index := make([]int, len(shape))
+	for i := range data {
+		// i is the row major position, find the column major one.
+		rem := i
+		for d := len(shape) - 1; d >= 0; d-- {
+			index[d] = rem % shape[d]
+			rem /= shape[d]
+		}
+		j, stride := 0, 1
+		for d := range shape {
+			j += index[d] * stride
+			stride *= shape[d]
+		}
+		out[i] = data[j]
+	}
+	return out
+}
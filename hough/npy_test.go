@@ -0,0 +1,183 @@
+package hough
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sameAccumulator reports how a differs from the accumulator e.
+func sameAccumulator(t *testing.T, e, a *Accumulator, input bool) {
+	t.Helper()
+	if a.Rect != e.Rect || a.MaxVal != e.MaxVal {
+		t.Fatalf("Expecting %v with max %d got %v with max %d", e.Rect, e.MaxVal, a.Rect, a.MaxVal)
+	}
+	for i := range e.Pix {
+		if a.Pix[i] != e.Pix[i] {
+			t.Fatalf("Expecting votes %d at %d got %d", e.Pix[i], i, a.Pix[i])
+		}
+	}
+	for _, c := range [][2]float64{
+		{e.MinAngle, a.MinAngle}, {e.MaxAngle, a.MaxAngle},
+		{e.MinDistance, a.MinDistance}, {e.MaxDistance, a.MaxDistance},
+	} {
+		if math.Abs(c[0]-c[1]) > 1e-9 {
+			t.Errorf("Expecting axes %g, %g, %g, %g got %g, %g, %g, %g",
+				e.MinAngle, e.MaxAngle, e.MinDistance, e.MaxDistance, a.MinAngle, a.MaxAngle, a.MinDistance, a.MaxDistance)
+			break
+		}
+	}
+	if input && (a.Origin != e.Origin || a.Input != e.Input) {
+		t.Errorf("Expecting origin %+v and input %v got %+v and %v", e.Origin, e.Input, a.Origin, a.Input)
+	}
+}
+
+func TestNPZRoundTrip(t *testing.T) {
+	acc, err := Transform(lineImage(60, 40, 12), Size(50, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dt := range []DType{Uint16, Uint32, Float32} {
+		var buf bytes.Buffer
+		if err := acc.WriteNPZ(&buf, dt); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadNPZ(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("dtype %d: %v", dt, err)
+		}
+		sameAccumulator(t, acc, read, true)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	acc, err := Transform(lineImage(60, 40, 12), Size(50, 30), AngleRange(0.5, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := acc.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written := buf.String()
+	read, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameAccumulator(t, acc, read, false)
+
+	// Without the ranges in the first cell they are recovered from the
+	// values of the axes.
+	legacy := `rho\theta` + written[strings.Index(written, ","+strconv.FormatFloat(acc.Angle(0), 'g', -1, 64)):]
+	if read, err = ReadCSV(strings.NewReader(legacy)); err != nil {
+		t.Fatal(err)
+	}
+	sameAccumulator(t, acc, read, false)
+}
+
+func TestOneWideRoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{50, 1}, {1, 30}} {
+		acc, err := Transform(lineImage(60, 40, 12), Size(size[0], size[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var npz, csv bytes.Buffer
+		if err := acc.WriteNPZ(&npz, Uint16); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadNPZ(bytes.NewReader(npz.Bytes()), int64(npz.Len()))
+		if err != nil {
+			t.Fatalf("%v npz: %v", size, err)
+		}
+		sameAccumulator(t, acc, read, true)
+		if err := acc.WriteCSV(&csv); err != nil {
+			t.Fatal(err)
+		}
+		if read, err = ReadCSV(&csv); err != nil {
+			t.Fatalf("%v csv: %v", size, err)
+		}
+		sameAccumulator(t, acc, read, false)
+	}
+}
+
+func TestNPYHeader(t *testing.T) {
+	acc, err := Transform(lineImage(20, 20, 5), Size(7, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := acc.WriteNPY(&buf, Uint32); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	headerLen := int(binary.LittleEndian.Uint16(b[8:10]))
+	if (10+headerLen)%64 != 0 {
+		t.Errorf("Expecting the data to be aligned to 64 bytes, it starts at %d", 10+headerLen)
+	}
+	header := string(b[10 : 10+headerLen])
+	if expected := "{'descr': '<u4', 'fortran_order': False, 'shape': (7, 9), }"; header[:len(expected)] != expected {
+		t.Errorf("Expecting header %q got %q", expected, header)
+	}
+	if len(b)-10-headerLen != 7*9*4 {
+		t.Errorf("Expecting %d bytes of data got %d", 7*9*4, len(b)-10-headerLen)
+	}
+}
+
+func TestReadNPYFortranBigEndian(t *testing.T) {
+	// A 2x3 big endian float array in column major order, with values that
+	// need rounding and saturating.
+	header := "{'descr': '>f4', 'fortran_order': True, 'shape': (2, 3), }\n"
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	for _, v := range []float32{1, 4, 2.4, 5, -3, 70000} {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	g, err := ReadNPY(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]uint16{{1, 2, 0}, {4, 5, math.MaxUint16}}
+	for y, row := range expected {
+		for x, e := range row {
+			if v := g.Gray16At(x, y).Y; v != e {
+				t.Errorf("Expecting %d at (%d, %d) got %d", e, x, y, v)
+			}
+		}
+	}
+	if g.MaxVal != math.MaxUint16 {
+		t.Errorf("Expecting max %d got %d", math.MaxUint16, g.MaxVal)
+	}
+}
+
+func TestReadNPYTruncated(t *testing.T) {
+	// A header claiming about 16GB of data followed by almost none must fail
+	// without allocating for the claimed shape.
+	header := "{'descr': '<f8', 'fortran_order': False, 'shape': (46340, 46340), }\n"
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	buf.Write(make([]byte, 100))
+	if _, err := ReadNPY(&buf); err == nil {
+		t.Error("Expecting an error for truncated data")
+	}
+}
+
+func TestReadNPYLongHeader(t *testing.T) {
+	// A version 2 header length of 4GiB must be refused before it is
+	// allocated.
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{2, 0})
+	binary.Write(&buf, binary.LittleEndian, uint32(math.MaxUint32))
+	if _, err := ReadNPY(&buf); err == nil {
+		t.Error("Expecting an error for an overlong header")
+	}
+}
@@ -0,0 +1,204 @@
+package hough
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/edge"
+	"github.com/piersy/hough-go/point"
+)
+
+// Predicate reports whether a pixel votes in the transform. It is given the
+// alpha-premultiplied colour components of the pixel as returned by
+// color.Color.RGBA.
+type Predicate = edge.Predicate
+
+// Weigher returns the total vote that a voting pixel contributes to the
+// accumulator, it is given the same colour components as a Predicate.
+type Weigher = edge.Weigher
+
+// Option configures a call to Transform.
+type Option func(*config)
+
+type config struct {
+	distances, angles  int
+	minAngle, maxAngle float64
+	origin             point.Point
+	originSet          bool
+	predicate          Predicate
+	weigher            Weigher
+	mask               image.Image
+	maskSet            bool
+	polygons           [][]point.Point
+	restrict           bool
+	progress           func(float64)
+	kernel             Kernel
+}
+
+func defaultConfig() config {
+	return config{
+		distances: 400,
+		angles:    400,
+		minAngle:  0,
+		maxAngle:  math.Pi,
+		predicate: Black,
+		weigher:   Constant(10),
+		kernel:    Linear,
+	}
+}
+
+// Size sets the shape of the accumulator, the number of distance buckets
+// (rows) and the number of angle buckets (columns). The default is 400x400.
+func Size(distances, angles int) Option {
+	return func(c *config) {
+		c.distances = distances
+		c.angles = angles
+	}
+}
+
+// AngleRange sets the half open range [min, max) of line angles, in radians,
+// that the angle buckets of the accumulator cover. The default is [0, Pi).
+func AngleRange(min, max float64) Option {
+	return func(c *config) {
+		c.minAngle = min
+		c.maxAngle = max
+	}
+}
+
+// Origin sets the point, in input image coordinates, from which the
+// perpendicular distance of lines is measured. The default is the centre of
+// the input image.
+func Origin(x, y float64) Option {
+	return func(c *config) {
+		c.origin = point.Point{X: x, Y: y}
+		c.originSet = true
+	}
+}
+
+// Votes sets the predicate that selects the pixels which vote. The default is
+// Black.
+func Votes(p Predicate) Option {
+	return func(c *config) {
+		c.predicate = p
+	}
+}
+
+// Weights sets the function that determines how much each voting pixel
+// contributes. The default is Constant(10).
+func Weights(w Weigher) Option {
+	return func(c *config) {
+		c.weigher = w
+	}
+}
+
+// Mask restricts voting to the pixels that are neither black nor transparent
+// in the mask image m. Pixels outside the bounds of m do not vote.
+func Mask(m image.Image) Option {
+	return func(c *config) {
+		c.mask = m
+		c.maskSet = true
+	}
+}
+
+// Polygons restricts voting to the pixels that lie inside at least one of the
+// given polygons, each polygon is given by its vertices in input image
+// coordinates. When combined with Mask a pixel must satisfy both to vote.
+func Polygons(polygons ...[]point.Point) Option {
+	return func(c *config) {
+		c.polygons = append(c.polygons, polygons...)
+	}
+}
+
+// RestrictDistances limits the distance range of the accumulator to the
+// distances of lines that pass through the region of interest defined by Mask
+// and Polygons, or through the input image if neither is set. This gives a
+// finer distance resolution for the same accumulator size.
+func RestrictDistances() Option {
+	return func(c *config) {
+		c.restrict = true
+	}
+}
+
+// Progress sets a function that is called as the rows of the input are
+// processed with the fraction of rows processed so far, the final call
+// reports 1. The function is called on the goroutine performing the
+// transform and so should return quickly.
+func Progress(f func(fraction float64)) Option {
+	return func(c *config) {
+		c.progress = f
+	}
+}
+
+// Spread sets the Kernel used to spread each vote over the accumulator. The
+// default is Linear.
+func Spread(k Kernel) Option {
+	return func(c *config) {
+		c.kernel = k
+	}
+}
+
+// Black is the default Predicate, it selects pixels that are black.
+func Black(r, g, b, a uint32) bool {
+	return edge.Black(r, g, b, a)
+}
+
+// Constant returns a Weigher that gives every voting pixel the vote v.
+func Constant(v float64) Weigher {
+	return edge.Constant(v)
+}
+
+// validate checks that the configuration describes a transform that can be
+// performed.
+func (c *config) validate() error {
+	if c.distances <= 0 || c.angles <= 0 {
+		return fmt.Errorf("hough: invalid accumulator size %dx%d, both dimensions must be positive", c.distances, c.angles)
+	}
+	if c.distances > math.MaxInt32/c.angles {
+		return fmt.Errorf("hough: accumulator size %dx%d is too large", c.distances, c.angles)
+	}
+	if !finite(c.minAngle) || !finite(c.maxAngle) || c.minAngle >= c.maxAngle {
+		return fmt.Errorf("hough: invalid angle range [%g, %g)", c.minAngle, c.maxAngle)
+	}
+	// Angles a and a+Pi describe the same set of lines, allowing a wider range
+	// would count every line twice.
+	if c.maxAngle-c.minAngle > math.Pi {
+		return fmt.Errorf("hough: angle range [%g, %g) is wider than Pi", c.minAngle, c.maxAngle)
+	}
+	if !finite(c.origin.X) || !finite(c.origin.Y) {
+		return fmt.Errorf("hough: invalid origin %+v", c.origin)
+	}
+	if c.predicate == nil {
+		return errors.New("hough: nil vote predicate")
+	}
+	if c.weigher == nil {
+		return errors.New("hough: nil vote weigher")
+	}
+	if c.kernel == nil {
+		return errors.New("hough: nil kernel")
+	}
+	if k, ok := c.kernel.(interface{ validate() error }); ok {
+		if err := k.validate(); err != nil {
+			return err
+		}
+	}
+	if c.maskSet && c.mask == nil {
+		return errors.New("hough: nil mask")
+	}
+	for i, p := range c.polygons {
+		if len(p) < 3 {
+			return fmt.Errorf("hough: polygon %d has %d vertices, at least 3 are required", i, len(p))
+		}
+		for _, v := range p {
+			if !finite(v.X) || !finite(v.Y) {
+				return fmt.Errorf("hough: polygon %d has invalid vertex %+v", i, v)
+			}
+		}
+	}
+	return nil
+}
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
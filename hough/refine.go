@@ -0,0 +1,206 @@
+package hough
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/piersy/hough-go/edge"
+)
+
+// FitMethod selects how Refine fits a line to its inliers.
+type FitMethod int
+
+const (
+	// LeastSquares fits the line minimising the sum of squared
+	// perpendicular distances of the inliers, it is the most precise when
+	// there are no outliers.
+	LeastSquares FitMethod = iota
+	// Huber fits the line by iteratively reweighted least squares with the
+	// Huber loss, which reduces the influence of outliers.
+	Huber
+	// RANSAC fits the line to the largest consensus set found by random
+	// sampling of pairs of inliers, then refines it by least squares on that
+	// set. It tolerates a large fraction of outliers.
+	RANSAC
+)
+
+// Refined is a line refined by fitting it to the pixels near it.
+type Refined struct {
+	// Line is the refined line, its votes are those of the original line.
+	Line
+	// Inliers is the number of pixels the line was fitted to.
+	Inliers int
+	// RMS is the root mean square and MaxResidual the largest perpendicular
+	// distance of the inliers from the refined line.
+	RMS, MaxResidual float64
+}
+
+// Refine improves the estimate of a line found in the accumulator, which is
+// limited by the size of the buckets, by fitting a line to the points of
+// edges that lie within band pixels of it. The points are usually those that
+// voted in the transform. Points are weighted by their weight. The refined
+// line is expressed relative to the Origin of the accumulator and with an
+// angle within Pi/2 of the original.
+func (a *Accumulator) Refine(edges *edge.List, l Line, band float64, method FitMethod) (Refined, error) {
+	if edges == nil {
+		return Refined{}, errors.New("hough: nil edge list")
+	}
+	if !(band > 0) {
+		return Refined{}, fmt.Errorf("hough: invalid band %g", band)
+	}
+	var pts []fitPoint
+	sin, cos := math.Sincos(l.Angle)
+	for _, p := range edges.Points {
+		x, y := float64(p.X)-a.Origin.X, float64(p.Y)-a.Origin.Y
+		if p.Weight > 0 && math.Abs(x*cos+y*sin-l.Distance) <= band {
+			pts = append(pts, fitPoint{x, y, p.Weight})
+		}
+	}
+	if len(pts) < 2 {
+		return Refined{}, fmt.Errorf("hough: %d points within %g pixels of the line, at least 2 are needed", len(pts), band)
+	}
+
+	var f fit
+	switch method {
+	case LeastSquares:
+		f = fitLeastSquares(pts)
+	case Huber:
+		f = fitHuber(pts)
+	case RANSAC:
+		f = fitRANSAC(pts, math.Max(1, band/4))
+	default:
+		return Refined{}, fmt.Errorf("hough: unknown fit method %d", method)
+	}
+	if !f.ok {
+		return Refined{}, errors.New("hough: inliers are degenerate, no line can be fitted")
+	}
+
+	r := Refined{
+		Line:    Line{Angle: f.angle, Distance: f.distance, Votes: l.Votes}.align(l.Angle),
+		Inliers: len(f.inliers),
+	}
+	fs, fc := math.Sincos(f.angle)
+	var sum, weights float64
+	for _, p := range f.inliers {
+		d := math.Abs(p.x*fc + p.y*fs - f.distance)
+		sum += p.w * d * d
+		weights += p.w
+		r.MaxResidual = math.Max(r.MaxResidual, d)
+	}
+	r.RMS = math.Sqrt(sum / weights)
+	return r, nil
+}
+
+type fitPoint struct {
+	x, y, w float64
+}
+
+type fit struct {
+	angle, distance float64
+	inliers         []fitPoint
+	ok              bool
+}
+
+// fitLeastSquares returns the orthogonal least squares fit of the points,
+// the line through their weighted centroid along the principal axis of their
+// scatter.
+func fitLeastSquares(pts []fitPoint) fit {
+	var mx, my, total float64
+	for _, p := range pts {
+		mx += p.w * p.x
+		my += p.w * p.y
+		total += p.w
+	}
+	if total == 0 {
+		return fit{}
+	}
+	mx /= total
+	my /= total
+	var sxx, syy, sxy float64
+	for _, p := range pts {
+		dx, dy := p.x-mx, p.y-my
+		sxx += p.w * dx * dx
+		syy += p.w * dy * dy
+		sxy += p.w * dx * dy
+	}
+	if sxx == 0 && syy == 0 {
+		return fit{}
+	}
+	// The direction of the line is the principal axis of the scatter, its
+	// normal is perpendicular to that.
+	direction := 0.5 * math.Atan2(2*sxy, sxx-syy)
+	angle := direction + math.Pi/2
+	sin, cos := math.Sincos(angle)
+	return fit{angle: angle, distance: mx*cos + my*sin, inliers: pts, ok: true}
+}
+
+// fitHuber fits the points by iteratively reweighted least squares with the
+// Huber loss. The scale of the residuals is estimated from their median
+// absolute deviation at each iteration.
+func fitHuber(pts []fitPoint) fit {
+	f := fitLeastSquares(pts)
+	weighted := make([]fitPoint, len(pts))
+	residuals := make([]float64, len(pts))
+	for i := 0; i < 20 && f.ok; i++ {
+		sin, cos := math.Sincos(f.angle)
+		for j, p := range pts {
+			residuals[j] = math.Abs(p.x*cos + p.y*sin - f.distance)
+		}
+		sorted := append([]float64(nil), residuals...)
+		sort.Float64s(sorted)
+		// 1.345 gives 95% efficiency for normally distributed residuals.
+		k := 1.345 * math.Max(sorted[len(sorted)/2]/0.6745, 1e-3)
+		for j, p := range pts {
+			w := p.w
+			if residuals[j] > k {
+				w *= k / residuals[j]
+			}
+			weighted[j] = fitPoint{p.x, p.y, w}
+		}
+		next := fitLeastSquares(weighted)
+		converged := next.ok && math.Abs(next.angle-f.angle) < 1e-9 && math.Abs(next.distance-f.distance) < 1e-9
+		f = next
+		if converged {
+			break
+		}
+	}
+	f.inliers = pts
+	return f
+}
+
+// fitRANSAC fits a line to the largest set of points lying within threshold
+// of a line through a random pair of the points. The random source is seeded
+// so that results are repeatable.
+func fitRANSAC(pts []fitPoint, threshold float64) fit {
+	rnd := rand.New(rand.NewSource(1))
+	var best []fitPoint
+	var bestWeight float64
+	for i := 0; i < 256; i++ {
+		p, q := pts[rnd.Intn(len(pts))], pts[rnd.Intn(len(pts))]
+		dx, dy := q.x-p.x, q.y-p.y
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		// The unit normal of the line through p and q.
+		nx, ny := -dy/length, dx/length
+		var consensus []fitPoint
+		var weight float64
+		for _, r := range pts {
+			if math.Abs((r.x-p.x)*nx+(r.y-p.y)*ny) <= threshold {
+				consensus = append(consensus, r)
+				weight += r.w
+			}
+		}
+		if weight > bestWeight {
+			best, bestWeight = consensus, weight
+		}
+	}
+	if len(best) < 2 {
+		return fit{}
+	}
+	return fitLeastSquares(best)
+}
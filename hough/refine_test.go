@@ -0,0 +1,66 @@
+package hough
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/piersy/hough-go/edge"
+)
+
+func TestRefine(t *testing.T) {
+	acc, err := Transform(image.NewGray(image.Rect(0, 0, 100, 100)), Size(10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	angle, distance := 0.7, 5.3
+	sin, cos := math.Sincos(angle)
+	rnd := rand.New(rand.NewSource(2))
+	list := &edge.List{Bounds: acc.Input}
+	for i := -40.0; i < 40; i++ {
+		// Points are rounded to pixels so carry up to 0.7 pixels of noise.
+		x := acc.Origin.X + distance*cos - i*sin
+		y := acc.Origin.Y + distance*sin + i*cos
+		list.Points = append(list.Points, edge.Point{X: int(math.Floor(x + 0.5)), Y: int(math.Floor(y + 0.5)), Weight: 1})
+	}
+	clean := len(list.Points)
+	// Outliers within the band.
+	for i := 0; i < 15; i++ {
+		x := acc.Origin.X + (distance+2.5)*cos - (rnd.Float64()*60-30)*sin
+		y := acc.Origin.Y + (distance+2.5)*sin + (rnd.Float64()*60-30)*cos
+		list.Points = append(list.Points, edge.Point{X: int(x), Y: int(y), Weight: 1})
+	}
+	coarse := Line{Angle: 0.75, Distance: 4.5, Votes: 7}
+
+	for _, c := range []struct {
+		name    string
+		method  FitMethod
+		points  []edge.Point
+		maxRMS  float64
+		inliers int
+	}{
+		{"least squares", LeastSquares, list.Points[:clean], 0.5, clean},
+		// Some outliers fall outside the band of the coarse line.
+		{"huber", Huber, list.Points, 1.5, 0},
+		{"ransac", RANSAC, list.Points, 0.5, 0},
+	} {
+		r, err := acc.Refine(&edge.List{Points: c.points, Bounds: list.Bounds}, coarse, 3, c.method)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if math.Abs(r.Angle-angle) > 0.01 || math.Abs(r.Distance-distance) > 0.15 {
+			t.Errorf("%s: expecting angle %.3f distance %.3f got %.4f %.4f", c.name, angle, distance, r.Angle, r.Distance)
+		}
+		if r.RMS > c.maxRMS || r.MaxResidual < r.RMS || r.Votes != coarse.Votes {
+			t.Errorf("%s: unexpected residuals or votes %+v", c.name, r)
+		}
+		if c.inliers > 0 && r.Inliers != c.inliers {
+			t.Errorf("%s: expecting %d inliers got %d", c.name, c.inliers, r.Inliers)
+		}
+	}
+
+	if _, err := acc.Refine(list, Line{Angle: 2, Distance: 40}, 1, LeastSquares); err == nil {
+		t.Error("Expecting error for a line without points got nil")
+	}
+}
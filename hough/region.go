@@ -0,0 +1,117 @@
+package hough
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/piersy/hough-go/point"
+)
+
+// region is the set of input pixels that are allowed to vote.
+type region struct {
+	// bounds is the smallest rectangle containing every pixel of the region.
+	bounds image.Rectangle
+	// input is the bounds of the input image, which inside is indexed by.
+	input image.Rectangle
+	// inside holds an entry for each pixel of input, it is nil if every
+	// pixel of input is part of the region.
+	inside []bool
+}
+
+// region builds the region of interest described by the mask and polygons of
+// the config.
+func (c *config) region(input image.Rectangle) region {
+	r := region{bounds: input, input: input}
+	if c.mask == nil && len(c.polygons) == 0 {
+		return r
+	}
+	r.inside = make([]bool, input.Dx()*input.Dy())
+	for i := range r.inside {
+		r.inside[i] = true
+	}
+	if c.mask != nil {
+		for y := input.Min.Y; y < input.Max.Y; y++ {
+			for x := input.Min.X; x < input.Max.X; x++ {
+				red, g, b, _ := c.mask.At(x, y).RGBA()
+				if red|g|b == 0 {
+					r.inside[r.offset(x, y)] = false
+				}
+			}
+		}
+	}
+	if len(c.polygons) > 0 {
+		inPolygon := make([]bool, len(r.inside))
+		for _, p := range c.polygons {
+			fillPolygon(p, input, func(x, y int) {
+				inPolygon[r.offset(x, y)] = true
+			})
+		}
+		for i, in := range inPolygon {
+			r.inside[i] = r.inside[i] && in
+		}
+	}
+
+	// Shrink the bounds to fit the pixels inside.
+	r.bounds = image.Rectangle{}
+	for y := input.Min.Y; y < input.Max.Y; y++ {
+		for x := input.Min.X; x < input.Max.X; x++ {
+			if r.inside[r.offset(x, y)] {
+				r.bounds = r.bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func (r region) offset(x, y int) int {
+	return (y-r.input.Min.Y)*r.input.Dx() + (x - r.input.Min.X)
+}
+
+// contains reports whether the pixel at (x, y) is part of the region, (x, y)
+// must lie within r.bounds.
+func (r region) contains(x, y int) bool {
+	return r.inside == nil || r.inside[r.offset(x, y)]
+}
+
+// distanceRange returns the range of perpendicular distances from origin of
+// lines at the given angles that pass through the bounds of the region.
+func (r region) distanceRange(origin point.Point, sinAngles, cosAngles []float64) (min, max float64) {
+	min, max = math.Inf(1), math.Inf(-1)
+	b := r.bounds
+	corners := []image.Point{b.Min, b.Max, image.Pt(b.Min.X, b.Max.Y), image.Pt(b.Max.X, b.Min.Y)}
+	for t := range sinAngles {
+		for _, p := range corners {
+			d := (float64(p.X)-origin.X)*cosAngles[t] + (float64(p.Y)-origin.Y)*sinAngles[t]
+			min = math.Min(min, d)
+			max = math.Max(max, d)
+		}
+	}
+	return min, max
+}
+
+// fillPolygon calls set for each pixel within bounds that lies inside the
+// polygon p according to the even-odd rule.
+func fillPolygon(p []point.Point, bounds image.Rectangle, set func(x, y int)) {
+	var crossings []float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		fy := float64(y)
+		crossings = crossings[:0]
+		// Find where each edge crosses this row.
+		for i := range p {
+			a, b := p[i], p[(i+1)%len(p)]
+			if (a.Y <= fy) != (b.Y <= fy) {
+				crossings = append(crossings, a.X+(fy-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+		}
+		sort.Float64s(crossings)
+		// Pixels between each pair of crossings are inside.
+		for i := 0; i+1 < len(crossings); i += 2 {
+			start := int(math.Max(math.Ceil(crossings[i]), float64(bounds.Min.X)))
+			end := int(math.Min(math.Ceil(crossings[i+1]), float64(bounds.Max.X)))
+			for x := start; x < end; x++ {
+				set(x, y)
+			}
+		}
+	}
+}
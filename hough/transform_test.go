@@ -0,0 +1,224 @@
+package hough
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/edge"
+	"github.com/piersy/hough-go/point"
+)
+
+// lineImage returns a white image with a single black horizontal line at y.
+func lineImage(w, h, y int) *image.RGBA {
+	im := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	for x := 0; x < w; x++ {
+		im.Set(x, y, color.Black)
+	}
+	return im
+}
+
+func TestTransformValidation(t *testing.T) {
+	im := lineImage(10, 10, 5)
+	cases := []struct {
+		name  string
+		input image.Image
+		opts  []Option
+	}{
+		{"nil input", nil, nil},
+		{"empty input", image.NewRGBA(image.Rect(0, 0, 0, 0)), nil},
+		{"zero size", im, []Option{Size(0, 10)}},
+		{"negative size", im, []Option{Size(10, -1)}},
+		{"reversed angles", im, []Option{AngleRange(1, 0)}},
+		{"angle range too wide", im, []Option{AngleRange(0, 4)}},
+		{"nan angle", im, []Option{AngleRange(math.NaN(), 1)}},
+		{"infinite origin", im, []Option{Origin(math.Inf(1), 0)}},
+		{"nil predicate", im, []Option{Votes(nil)}},
+		{"nil weigher", im, []Option{Weights(nil)}},
+	}
+	for _, c := range cases {
+		if _, err := Transform(c.input, c.opts...); err == nil {
+			t.Errorf("%s: expecting error got nil", c.name)
+		}
+	}
+}
+
+func TestTransformHorizontalLine(t *testing.T) {
+	im := lineImage(40, 40, 30)
+	acc, err := Transform(im, Size(80, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Bounds() != image.Rect(0, 0, 90, 80) {
+		t.Fatalf("Expecting accumulator bounds %v got %v", image.Rect(0, 0, 90, 80), acc.Bounds())
+	}
+	var max uint16
+	var maxAt image.Point
+	for y := 0; y < acc.Rect.Dy(); y++ {
+		for x := 0; x < acc.Rect.Dx(); x++ {
+			if v := acc.Gray16At(x, y).Y; v > max {
+				max = v
+				maxAt = image.Pt(x, y)
+			}
+		}
+	}
+	if max != acc.MaxVal {
+		t.Errorf("Expecting MaxVal %d got %d", max, acc.MaxVal)
+	}
+	angle := acc.Angle(float64(maxAt.X))
+	if math.Abs(angle-math.Pi/2) > 0.04 {
+		t.Errorf("Expecting angle %.3f got %.3f", math.Pi/2, angle)
+	}
+	// The line is 10 pixels below the centre of the image.
+	distance := acc.Distance(float64(maxAt.Y))
+	if math.Abs(distance-10) > 1 {
+		t.Errorf("Expecting distance %.3f got %.3f", 10.0, distance)
+	}
+}
+
+func TestTransformPredicate(t *testing.T) {
+	im := lineImage(10, 10, 5)
+	acc, err := Transform(im, Votes(func(r, g, b, a uint32) bool { return false }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.MaxVal != 0 {
+		t.Errorf("Expecting no votes got max %d", acc.MaxVal)
+	}
+}
+
+func TestTransformRegionOfInterest(t *testing.T) {
+	im := lineImage(40, 40, 30)
+	for x := 0; x < 40; x++ {
+		im.Set(x, 5, color.Black)
+	}
+	// Only the lower half of the image, containing the line at y=30, votes.
+	lower := []point.Point{{X: 0, Y: 20}, {X: 40, Y: 20}, {X: 40, Y: 40}, {X: 0, Y: 40}}
+	mask := image.NewGray(im.Bounds())
+	draw.Draw(mask, image.Rect(0, 20, 40, 40), image.NewUniform(color.White), image.ZP, draw.Src)
+
+	for name, opt := range map[string]Option{"polygon": Polygons(lower), "mask": Mask(mask)} {
+		acc, err := Transform(im, Size(80, 90), opt, RestrictDistances())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// No point of the region lies more than 20 pixels above or left of
+		// the centre.
+		if acc.MinDistance < -21 {
+			t.Errorf("%s: expecting restricted distance range got [%.3f, %.3f]", name, acc.MinDistance, acc.MaxDistance)
+		}
+		x := int(acc.AngleBin(math.Pi / 2))
+		if v := acc.Gray16At(x, int(acc.DistanceBin(-15))).Y; v != 0 {
+			t.Errorf("%s: expecting no votes for the line outside the region got %d", name, v)
+		}
+		if v := acc.Gray16At(x, int(acc.DistanceBin(10))).Y; v == 0 {
+			t.Errorf("%s: expecting votes for the line inside the region", name)
+		}
+	}
+}
+
+func TestTransformContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	acc, err := TransformContext(ctx, lineImage(10, 10, 5))
+	if err != context.Canceled || acc != nil {
+		t.Errorf("Expecting nil accumulator and %v got %v and %v", context.Canceled, acc, err)
+	}
+}
+
+func TestTransformProgress(t *testing.T) {
+	var reports []float64
+	// Enough black pixels for several batches of votes.
+	im := image.NewRGBA(image.Rect(0, 0, 100, 200))
+	_, err := Transform(im, Progress(func(f float64) {
+		reports = append(reports, f)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) < 2 {
+		t.Fatalf("Expecting multiple progress reports got %d", len(reports))
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i] <= reports[i-1] {
+			t.Errorf("Expecting increasing progress got %v", reports)
+			break
+		}
+	}
+	if reports[len(reports)-1] != 1 {
+		t.Errorf("Expecting final progress of 1 got %v", reports[len(reports)-1])
+	}
+}
+
+func TestTransformProgressEmpty(t *testing.T) {
+	var reports []float64
+	progress := Progress(func(f float64) {
+		reports = append(reports, f)
+	})
+	// A region of interest entirely outside the input has no rows.
+	outside := []point.Point{{X: 200, Y: 200}, {X: 210, Y: 200}, {X: 210, Y: 210}}
+	if _, err := Transform(image.NewRGBA(image.Rect(0, 0, 20, 20)), Polygons(outside), progress); err != nil {
+		t.Fatal(err)
+	}
+	edges := &edge.List{Bounds: image.Rect(0, 0, 20, 20)}
+	if _, err := TransformEdges(context.Background(), edges, progress); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0] != 1 || reports[1] != 1 {
+		t.Errorf("Expecting a final progress of 1 from each transform got %v", reports)
+	}
+}
+
+// box is a Kernel implemented outside those provided, it shares the vote
+// equally between the nearest distance bucket and those either side.
+type box struct{}
+
+func (box) Spread(dist float64, add func(angle, distance int, share float64)) {
+	d := int(math.Floor(dist + 0.5))
+	for i := -1; i <= 1; i++ {
+		add(0, d+i, 1.0/3)
+	}
+}
+
+func TestTransformKernels(t *testing.T) {
+	im := lineImage(40, 40, 30)
+	for _, k := range []Kernel{Nearest, Linear, Gaussian(1.5, 1), Gaussian(0, 2), box{}} {
+		acc, err := Transform(im, Size(80, 90), Spread(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every kernel should leave the peak on the line.
+		x := int(acc.AngleBin(math.Pi/2) + 0.5)
+		y := int(acc.DistanceBin(10) + 0.5)
+		if acc.Gray16At(x, y).Y != acc.MaxVal {
+			t.Errorf("%#v: expecting peak of %d at %v got %d", k, acc.MaxVal, image.Pt(x, y), acc.Gray16At(x, y).Y)
+		}
+	}
+	for _, k := range []Kernel{Gaussian(-1, 1), Gaussian(1, math.NaN()), Gaussian(100, 1)} {
+		if _, err := Transform(im, Spread(k)); err == nil {
+			t.Errorf("%#v: expecting error got nil", k)
+		}
+	}
+}
+
+func TestTransformEdges(t *testing.T) {
+	im := lineImage(40, 30, 12)
+	im.Set(3, 25, color.Black)
+	fromImage, err := Transform(im, Size(60, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromEdges, err := TransformEdges(context.Background(), edge.Extract(im, edge.Weigh(edge.Constant(10))), Size(60, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range fromImage.Pix {
+		if fromImage.Pix[i] != fromEdges.Pix[i] {
+			t.Fatalf("Expecting the same accumulator from the image and its edges, differs at %d", i)
+		}
+	}
+}
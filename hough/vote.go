@@ -0,0 +1,57 @@
+package hough
+
+import (
+	"math"
+
+	"github.com/piersy/hough-go/edge"
+)
+
+// voteBatch is the number of edges collected before they vote. Larger
+// batches make better use of the cache since the votes for each angle are
+// loaded once per batch.
+const voteBatch = 4096
+
+// vote casts the votes of the edges, which must lie within the region of
+// interest, for all angles. The votes for an angle are held contiguously so
+// iterating the angles in the outer loop keeps them in the cache while each
+// edge votes.
+func (t *transform) vote(edges []edge.Point) {
+	if len(edges) == 0 {
+		return
+	}
+	v, k := t.votes, t.config.kernel
+	min := t.roi.bounds.Min
+	w, h := t.roi.bounds.Dx(), t.roi.bounds.Dy()
+	for a := 0; a < v.angles; a++ {
+		xt := t.xs[a*w : (a+1)*w]
+		yt := t.ys[a*h : (a+1)*h]
+		// The common linear kernel is inlined to avoid the cost of an
+		// interface call per vote.
+		if _, ok := k.(linear); ok {
+			votes := v.v[a*v.distances : (a+1)*v.distances]
+			for _, e := range edges {
+				if !(e.Weight > 0) {
+					continue
+				}
+				// As in linear.spread, a distance just below 0 still gives
+				// part of its vote to bucket 0.
+				dist := xt[e.X-min.X] + yt[e.Y-min.Y]
+				intDist := math.Floor(dist)
+				floatingPointPart := dist - intDist
+				d := int(intDist)
+				if d+1 >= 0 && d+1 < len(votes) {
+					votes[d+1] += float32(e.Weight * floatingPointPart)
+				}
+				if d >= 0 && d < len(votes) {
+					votes[d] += float32(e.Weight * (1.0 - floatingPointPart))
+				}
+			}
+			continue
+		}
+		for _, e := range edges {
+			if e.Weight > 0 {
+				v.spread(k, a, xt[e.X-min.X]+yt[e.Y-min.Y], e.Weight)
+			}
+		}
+	}
+}
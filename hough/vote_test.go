@@ -0,0 +1,74 @@
+package hough
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/edge"
+)
+
+// slowLinear is the linear kernel hidden behind another type, so that votes
+// are spread through the Kernel interface rather than the inlined path.
+type slowLinear struct {
+	linear
+}
+
+func TestVoteLinearPaths(t *testing.T) {
+	// Distances either side of every bucket boundary, including just below
+	// the first bucket and just above the last.
+	xs := []float64{-1.5, -0.75, -0.25, 0, 0.5, 1.25, 2.75, 3.5, 4.5}
+	edges := make([]edge.Point, len(xs))
+	for i := range edges {
+		edges[i] = edge.Point{X: i, Y: 0, Weight: float64(i + 1)}
+	}
+	accumulate := func(k Kernel) []float32 {
+		tr := &transform{
+			config: config{kernel: k},
+			roi:    region{bounds: image.Rect(0, 0, len(xs), 1)},
+			votes:  newVotes(4, 1),
+			xs:     xs,
+			ys:     []float64{0},
+		}
+		tr.vote(edges)
+		return tr.votes.v
+	}
+	fast, slow := accumulate(Linear), accumulate(slowLinear{})
+	for i := range slow {
+		if fast[i] != slow[i] {
+			t.Errorf("Expecting the inlined linear kernel to give %v got %v", slow, fast)
+			break
+		}
+	}
+	if fast[0] == 0 {
+		t.Error("Expecting votes just below the first bucket to count towards it")
+	}
+}
+
+func TestVoteWrap(t *testing.T) {
+	// A vote in the first angle bucket spread by a gaussian over the angles
+	// either side, with no spread along the distance.
+	spread := func(wrap bool) (*votes, float64) {
+		v := newVotes(4, 10)
+		v.wrap, v.negate = wrap, 4
+		v.spread(Gaussian(0, 1), 0, 1.2, 1)
+		var total float64
+		for _, w := range v.v {
+			total += float64(w)
+		}
+		return v, total
+	}
+	v, total := spread(true)
+	if math.Abs(total-1) > 1e-6 {
+		t.Errorf("Expecting no votes to be lost when wrapping got %g of 1", total)
+	}
+	// The share spread below the first angle lands in the last angle at the
+	// negated distance, 4-1.2 rounded to bucket 3, and matches the share in
+	// the second angle.
+	if last, second := v.v[9*4+3], v.v[1*4+1]; second == 0 || last != second {
+		t.Errorf("Expecting %g wrapped to the last angle got %g", second, last)
+	}
+	if v, total := spread(false); v.v[9*4+3] != 0 || !(total < 1) {
+		t.Errorf("Expecting the share beyond the angles to be dropped without wrapping got %v", v.v)
+	}
+}
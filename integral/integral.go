@@ -0,0 +1,93 @@
+// Package integral provides summed-area tables of gray16 images, from which
+// the sum, mean and variance of the pixels of any rectangle are found in
+// constant time.
+package integral
+
+import (
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// Table is a summed-area table. The entry for (x, y) holds the sum of the
+// pixels, or of their squares, above and to the left of it, so the table has
+// a row and column more than the image.
+type Table struct {
+	sums   []uint64
+	stride int
+	// Rect is the bounds of the image the table was made from.
+	Rect image.Rectangle
+}
+
+// New returns the summed-area table of the pixels of g.
+func New(g *gray16.Gray16) *Table {
+	return build(g, func(v uint64) uint64 { return v })
+}
+
+// NewSquared returns the summed-area table of the squares of the pixels of
+// g, which with the table of New gives the variance, see Variance.
+func NewSquared(g *gray16.Gray16) *Table {
+	return build(g, func(v uint64) uint64 { return v * v })
+}
+
+func build(g *gray16.Gray16, f func(uint64) uint64) *Table {
+	b := g.Rect
+	w, h := b.Dx(), b.Dy()
+	t := &Table{sums: make([]uint64, (w+1)*(h+1)), stride: w + 1, Rect: b}
+	for y := 0; y < h; y++ {
+		i := g.PixOffset(b.Min.X, b.Min.Y+y)
+		above, row := t.sums[y*t.stride:], t.sums[(y+1)*t.stride:]
+		var sum uint64
+		for x, v := range g.Pix[i : i+w] {
+			sum += f(uint64(v))
+			row[x+1] = above[x+1] + sum
+		}
+	}
+	return t
+}
+
+// Sum returns the sum over the pixels of r, clipped to the bounds of the
+// image.
+func (t *Table) Sum(r image.Rectangle) uint64 {
+	r = r.Intersect(t.Rect)
+	if r.Empty() {
+		return 0
+	}
+	x0, y0 := r.Min.X-t.Rect.Min.X, r.Min.Y-t.Rect.Min.Y
+	x1, y1 := r.Max.X-t.Rect.Min.X, r.Max.Y-t.Rect.Min.Y
+	return t.sums[y1*t.stride+x1] - t.sums[y0*t.stride+x1] - t.sums[y1*t.stride+x0] + t.sums[y0*t.stride+x0]
+}
+
+// Mean returns the mean over the pixels of r, clipped to the bounds of the
+// image. An empty rectangle has a mean of 0.
+func (t *Table) Mean(r image.Rectangle) float64 {
+	r = r.Intersect(t.Rect)
+	if r.Empty() {
+		return 0
+	}
+	return float64(t.Sum(r)) / float64(r.Dx()*r.Dy())
+}
+
+// Variance returns the variance of the pixels of r, clipped to the bounds of
+// the image, from the tables of the pixels and of their squares of the same
+// image.
+func Variance(sum, squared *Table, r image.Rectangle) float64 {
+	mean := sum.Mean(r)
+	v := squared.Mean(r) - mean*mean
+	// Rounding may leave a tiny negative variance for flat regions.
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// StdDev returns the standard deviation of the pixels of r, see Variance.
+func StdDev(sum, squared *Table, r image.Rectangle) float64 {
+	return math.Sqrt(Variance(sum, squared, r))
+}
+
+// Window returns the square of side 2*radius+1 centred on the pixel at p.
+func Window(p image.Point, radius int) image.Rectangle {
+	return image.Rect(p.X-radius, p.Y-radius, p.X+radius+1, p.Y+radius+1)
+}
@@ -0,0 +1,61 @@
+package integral
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+func TestTable(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(-4, 3, 13, 15))
+	rnd := rand.New(rand.NewSource(1))
+	for i := range g.Pix {
+		g.Pix[i] = uint16(rnd.Intn(math.MaxUint16 + 1))
+	}
+	sum, squared := New(g), NewSquared(g)
+	for n := 0; n < 200; n++ {
+		r := image.Rect(rnd.Intn(24)-8, rnd.Intn(20), rnd.Intn(24)-8, rnd.Intn(20)).Canon()
+		var s, sq float64
+		clipped := r.Intersect(g.Rect)
+		for y := clipped.Min.Y; y < clipped.Max.Y; y++ {
+			for x := clipped.Min.X; x < clipped.Max.X; x++ {
+				v := float64(g.Gray16At(x, y).Y)
+				s += v
+				sq += v * v
+			}
+		}
+		if got := sum.Sum(r); float64(got) != s {
+			t.Fatalf("Expecting the sum over %v to be %g got %d", r, s, got)
+		}
+		if got := squared.Sum(r); float64(got) != sq {
+			t.Fatalf("Expecting the sum of squares over %v to be %g got %d", r, sq, got)
+		}
+		if clipped.Empty() {
+			continue
+		}
+		count := float64(clipped.Dx() * clipped.Dy())
+		mean := s / count
+		if m := sum.Mean(r); math.Abs(m-mean) > 1e-6 {
+			t.Errorf("Expecting the mean over %v to be %g got %g", r, mean, m)
+		}
+		variance := sq/count - mean*mean
+		if v := Variance(sum, squared, r); math.Abs(v-variance) > 1e-3*variance+1e-6 {
+			t.Errorf("Expecting the variance over %v to be %g got %g", r, variance, v)
+		}
+	}
+
+	c := gray16.NewGray16(image.Rect(0, 0, 3, 3))
+	for i := range c.Pix {
+		c.Pix[i] = 40000
+	}
+	w := Window(image.Pt(1, 1), 1)
+	if v := StdDev(New(c), NewSquared(c), w); v != 0 {
+		t.Errorf("Expecting no deviation in a flat image got %g", v)
+	}
+	if m := New(c).Mean(image.Rect(5, 5, 6, 6)); m != 0 {
+		t.Errorf("Expecting an empty rectangle to have a mean of 0 got %g", m)
+	}
+}
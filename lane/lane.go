@@ -0,0 +1,226 @@
+// Package lane detects the lane markings either side of a vehicle in road
+// images, such as those from a forward facing dashcam.
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/piersy/hough-go/canvas"
+	"github.com/piersy/hough-go/hough"
+	"github.com/piersy/hough-go/point"
+)
+
+// Lane is a detected lane marking.
+type Lane struct {
+	// Line is the line of the marking in the hough transform.
+	Line hough.Line
+	// Bottom is where the marking meets the bottom of the image and Top
+	// where it meets the horizon.
+	Bottom, Top point.Point
+}
+
+// Lanes holds the lane markings either side of the vehicle, a side is nil if
+// no marking was found on it.
+type Lanes struct {
+	Left, Right *Lane
+}
+
+// Option configures lane detection.
+type Option func(*config)
+
+type config struct {
+	horizon, topWidth  float64
+	minAngle, maxAngle float64
+	minVotes           float64
+}
+
+// Horizon sets the height of the horizon as a fraction of the image height
+// measured from the top. Only pixels below the horizon are searched and lanes
+// are extrapolated up to it. The default is 0.6.
+func Horizon(f float64) Option {
+	return func(c *config) {
+		c.horizon = f
+	}
+}
+
+// TopWidth sets the width of the top of the trapezoidal region searched for
+// lanes as a fraction of the image width, the bottom spans the whole width.
+// The default is 0.2.
+func TopWidth(f float64) Option {
+	return func(c *config) {
+		c.topWidth = f
+	}
+}
+
+// Angles sets the range of angles, in radians from the horizontal, that lane
+// markings may lie at. Markings near horizontal, such as stop lines, and near
+// vertical are ignored. The default is 20 to 70 degrees.
+func Angles(min, max float64) Option {
+	return func(c *config) {
+		c.minAngle = min
+		c.maxAngle = max
+	}
+}
+
+// MinVotes sets the minimum score a line must reach in the hough transform
+// to be considered a lane marking. The default is 200, the score of a fully
+// covered line about 20 pixels long.
+func MinVotes(v float64) Option {
+	return func(c *config) {
+		c.minVotes = v
+	}
+}
+
+// Detect finds the lane markings in im. The image is converted to
+// grayscale and the white and yellow pixels that lane markings are painted
+// with are selected. Only selected pixels inside a trapezoid stretching from
+// the bottom of the image to the horizon vote in a hough transform
+// constrained to the angles lane markings lie at. The strongest line leaning
+// each way is taken as the lane marking on that side and extrapolated from
+// the bottom of the image to the horizon.
+func Detect(im image.Image, opts ...Option) (*Lanes, error) {
+	c := config{
+		horizon:  0.6,
+		topWidth: 0.2,
+		minAngle: 20 * math.Pi / 180,
+		maxAngle: 70 * math.Pi / 180,
+		minVotes: 200,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	if !(c.horizon >= 0 && c.horizon < 1) {
+		return nil, fmt.Errorf("lane: invalid horizon %g", c.horizon)
+	}
+	if !(c.topWidth > 0 && c.topWidth <= 1) {
+		return nil, fmt.Errorf("lane: invalid top width %g", c.topWidth)
+	}
+	if !(c.minAngle > 0 && c.minAngle < c.maxAngle && c.maxAngle < math.Pi/2) {
+		return nil, fmt.Errorf("lane: invalid angles [%g, %g]", c.minAngle, c.maxAngle)
+	}
+	if im == nil {
+		return nil, errors.New("lane: nil image")
+	}
+
+	selected := Select(im)
+	b := im.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	horizon := float64(b.Min.Y) + c.horizon*h
+	centre := float64(b.Min.X) + w/2
+	roi := []point.Point{
+		{X: float64(b.Min.X), Y: float64(b.Max.Y)},
+		{X: centre - c.topWidth*w/2, Y: horizon},
+		{X: centre + c.topWidth*w/2, Y: horizon},
+		{X: float64(b.Max.X), Y: float64(b.Max.Y)},
+	}
+
+	// A marking on the left rises to the right, so a marking at angle a from
+	// the horizontal has its normal at Pi/2-a, a marking on the right is its
+	// mirror image with its normal at Pi/2+a.
+	lanes := &Lanes{}
+	for _, side := range []struct {
+		lane       **Lane
+		minA, maxA float64
+	}{
+		{&lanes.Left, math.Pi/2 - c.maxAngle, math.Pi/2 - c.minAngle},
+		{&lanes.Right, math.Pi/2 + c.minAngle, math.Pi/2 + c.maxAngle},
+	} {
+		acc, err := hough.Transform(selected,
+			hough.Size(int(math.Hypot(w, h)), int(math.Ceil((side.maxA-side.minA)*180/math.Pi*2))),
+			hough.AngleRange(side.minA, side.maxA),
+			hough.Polygons(roi),
+			hough.Votes(func(r, g, b, a uint32) bool { return r != 0 }),
+		)
+		if err != nil {
+			return nil, err
+		}
+		peak := strongest(acc)
+		l := acc.LineAt(peak)
+		if l.Votes < c.minVotes {
+			continue
+		}
+		*side.lane = &Lane{
+			Line:   l,
+			Bottom: point.Point{X: atY(acc, l, float64(b.Max.Y)), Y: float64(b.Max.Y)},
+			Top:    point.Point{X: atY(acc, l, horizon), Y: horizon},
+		}
+	}
+	return lanes, nil
+}
+
+// Select returns a mask of the pixels of im that have the colour of lane
+// markings, selected pixels are white and others black. Markings are either
+// white, which is picked out of the grayscale image by its brightness, or
+// yellow.
+func Select(im image.Image) *image.Gray {
+	b := im.Bounds()
+	gray := image.NewGray(b)
+	draw.Draw(gray, b, im, b.Min, draw.Src)
+	mask := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			white := gray.GrayAt(x, y).Y >= 200
+			r, g, bl, _ := im.At(x, y).RGBA()
+			yellow := r >= 0x8000 && g >= 0x8000 && bl < (r+g)/2*6/10
+			if white || yellow {
+				mask.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	return mask
+}
+
+// strongest returns the position of the highest scoring bucket of acc.
+func strongest(acc *hough.Accumulator) point.Point {
+	var best uint16
+	var at point.Point
+	for y := acc.Rect.Min.Y; y < acc.Rect.Max.Y; y++ {
+		for x := acc.Rect.Min.X; x < acc.Rect.Max.X; x++ {
+			if v := acc.Gray16At(x, y).Y; v > best {
+				best = v
+				at = point.Point{X: float64(x), Y: float64(y)}
+			}
+		}
+	}
+	return at
+}
+
+// atY returns the x coordinate at which the line l of acc crosses the image
+// row y.
+func atY(acc *hough.Accumulator, l hough.Line, y float64) float64 {
+	sin, cos := math.Sincos(l.Angle)
+	return acc.Origin.X + (l.Distance-(y-acc.Origin.Y)*sin)/cos
+}
+
+// Overlay returns a copy of im with the detected lanes drawn over it, the
+// left lane in green and the right in red.
+func Overlay(im image.Image, lanes *Lanes) *image.RGBA {
+	b := im.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, im, b.Min, draw.Src)
+	ctx := canvas.New()
+	for _, l := range []struct {
+		lane *Lane
+		c    color.Color
+	}{
+		{lanes.Left, color.RGBA{0, 255, 0, 255}},
+		{lanes.Right, color.RGBA{255, 0, 0, 255}},
+	} {
+		if l.lane == nil {
+			continue
+		}
+		ctx.Color(l.c)
+		// Draw the lane three pixels wide so that it stands out.
+		for dx := -1; dx <= 1; dx++ {
+			ctx.MoveTo(image.Pt(int(l.lane.Bottom.X+0.5)+dx, int(l.lane.Bottom.Y+0.5)))
+			ctx.LineTo(image.Pt(int(l.lane.Top.X+0.5)+dx, int(l.lane.Top.Y+0.5)))
+		}
+	}
+	ctx.Render(out)
+	return out
+}
@@ -0,0 +1,88 @@
+package lane
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// road returns an image of a gray road with a white marking on the left and
+// a yellow marking on the right, both heading towards the point (160, 100).
+func road() *image.RGBA {
+	im := image.NewRGBA(image.Rect(0, 0, 320, 200))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.RGBA{60, 60, 60, 255}), image.ZP, draw.Src)
+	for y := 120; y < 200; y++ {
+		f := float64(y-100) / 100
+		for w := -2; w <= 2; w++ {
+			im.Set(int(160-120*f)+w, y, color.RGBA{240, 240, 240, 255})
+			im.Set(int(160+130*f)+w, y, color.RGBA{230, 200, 40, 255})
+		}
+	}
+	return im
+}
+
+func TestDetect(t *testing.T) {
+	lanes, err := Detect(road(), Horizon(0.55))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lanes.Left == nil || lanes.Right == nil {
+		t.Fatalf("Expecting both lanes got %+v", lanes)
+	}
+	// The markings meet the bottom of the image at 40 and 290 and the
+	// horizon, at y=110, at 148 and 173.
+	for _, c := range []struct {
+		name     string
+		got, exp float64
+	}{
+		{"left bottom", lanes.Left.Bottom.X, 40},
+		{"left top", lanes.Left.Top.X, 148},
+		{"right bottom", lanes.Right.Bottom.X, 290},
+		{"right top", lanes.Right.Top.X, 173},
+	} {
+		if math.Abs(c.got-c.exp) > 3 {
+			t.Errorf("%s: expecting x of %.1f got %.1f", c.name, c.exp, c.got)
+		}
+	}
+	if math.Abs(lanes.Left.Top.Y-110) > 1e-9 || lanes.Left.Bottom.Y != 200 {
+		t.Errorf("Expecting left lane from y=200 to y=110 got %+v", lanes.Left)
+	}
+}
+
+func TestDetectAngles(t *testing.T) {
+	// The markings lie at about 40 and 38 degrees from the horizontal, a
+	// range that is not symmetric about 45 degrees still finds them.
+	lanes, err := Detect(road(), Horizon(0.55), Angles(10*math.Pi/180, 42*math.Pi/180))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lanes.Left == nil || lanes.Right == nil {
+		t.Fatalf("Expecting both lanes got %+v", lanes)
+	}
+	for _, c := range []struct {
+		name     string
+		got, exp float64
+	}{
+		{"left bottom", lanes.Left.Bottom.X, 40},
+		{"left top", lanes.Left.Top.X, 148},
+		{"right bottom", lanes.Right.Bottom.X, 290},
+		{"right top", lanes.Right.Top.X, 173},
+	} {
+		if math.Abs(c.got-c.exp) > 3 {
+			t.Errorf("%s: expecting x of %.1f got %.1f", c.name, c.exp, c.got)
+		}
+	}
+}
+
+func TestDetectNoLanes(t *testing.T) {
+	im := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	lanes, err := Detect(im)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lanes.Left != nil || lanes.Right != nil {
+		t.Errorf("Expecting no lanes got %+v", lanes)
+	}
+}
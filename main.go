@@ -10,19 +10,51 @@ import (
 	_ "image/jpeg"
 	"image/png"
 	_ "image/png"
+	"io"
 	"math"
 	"os"
+	"path/filepath"
 
 	"github.com/piersy/hough-go/blob"
 	"github.com/piersy/hough-go/canvas"
 	"github.com/piersy/hough-go/conv"
+	"github.com/piersy/hough-go/deskew"
+	"github.com/piersy/hough-go/detect"
+	"github.com/piersy/hough-go/enhance"
+	"github.com/piersy/hough-go/gray16"
+	"github.com/piersy/hough-go/heatmap"
 	"github.com/piersy/hough-go/hough"
-	"github.com/piersy/hough-go/norm"
+	"github.com/piersy/hough-go/netpbm"
+	"github.com/piersy/hough-go/report"
+	"github.com/piersy/hough-go/thresh"
 )
 
 var (
 	in  = flag.String("in", "", "input image")
-	out = flag.String("out", "", "output image")
+	out = flag.String("out", "", "output image, written as png or, by its extension, as pbm, pgm, ppm or pnm")
+
+	mergeAngle    = flag.Float64("merge-angle", 2, "angle tolerance in degrees for merging detected lines")
+	mergeDistance = flag.Float64("merge-distance", 5, "distance tolerance in pixels for merging detected lines")
+
+	fast = flag.Bool("fast", false, "use the fast hough transform, which is quicker on large images but less precise")
+
+	npzOut = flag.String("npz", "", "write the raw accumulator, with its axes, to this NumPy .npz file")
+	csvOut = flag.String("csv", "", "write the raw accumulator, with its axes, to this CSV file")
+
+	heatmapOut = flag.String("heatmap", "", "write the raw accumulator in false colour, with axes and a colour bar, to this png file")
+	colours    = flag.String("colours", "viridis", "colour map of the heatmap, one of viridis, inferno, jet or grayscale")
+	scale      = flag.String("scale", "log", "scaling of the heatmap, one of linear, log or equalise")
+
+	reportOut = flag.String("report", "", "write an annotated report of the lines detected, drawn on the input and marked on the accumulator, to this png file")
+
+	threshold      = flag.String("threshold", "adaptive", "threshold applied to the filtered accumulator to find peaks, adaptive or one of the automatic thresholds otsu, triangle or kapur")
+	inputThreshold = flag.String("input-threshold", "", "select the pixels that vote by an automatic threshold of the input, one of otsu, triangle or kapur, rather than only black pixels")
+
+	enhanceAcc = flag.String("enhance", "", "enhance the contrast of the filtered accumulator before thresholding, equalise, a contrast limited equalisation of the whole accumulator, or clahe, rather than stretching it linearly")
+
+	detector = flag.String("detector", "hough", "line detector to use, one of hough, ransac or lsd. Detectors other than hough draw the segments found on the input and write it to the output image")
+
+	deskewIn = flag.Bool("deskew", false, "write the input corrected for skew to the output image instead of the hough transform")
 )
 
 type cn struct {
@@ -43,37 +75,223 @@ func main() {
 		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
+	if *deskewIn {
+		upright, angle, err := deskew.Deskew(baseImage)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Skew: %.3f degrees\n", angle*180/math.Pi)
+		outFile, err := os.Create(*out)
+		defer outFile.Close()
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		if err := encode(outFile, *out, upright); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *detector != "hough" {
+		var d detect.Detector
+		switch *detector {
+		case "ransac":
+			d = detect.RANSAC{}
+		case "lsd":
+			d = detect.LSD{}
+		default:
+			fmt.Printf("unknown detector %q\n", *detector)
+			os.Exit(1)
+		}
+		segments, err := d.Detect(baseImage)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		ctx := canvas.New()
+		ctx.Color(color.NRGBA{255, 0, 0, 255})
+		for i, s := range segments {
+			fmt.Printf("Segment %d: %+v\n", i, s)
+			ctx.MoveTo(image.Pt(int(s.From.X), int(s.From.Y)))
+			ctx.LineTo(image.Pt(int(s.To.X), int(s.To.Y)))
+		}
+		outFile, err := os.Create(*out)
+		defer outFile.Close()
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		// Decoded images such as *image.YCbCr cannot be drawn on, so draw
+		// on a copy.
+		annotated := image.NewRGBA(baseImage.Bounds())
+		draw.Draw(annotated, annotated.Bounds(), baseImage, annotated.Bounds().Min, draw.Src)
+		ctx.Render(annotated)
+		if err := encode(outFile, *out, annotated); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	accAngles := 400
 	accDistances := 400
-	acc := hough.Hough(baseImage, accDistances, accAngles)
+	transform := hough.Transform
+	if *fast {
+		transform = hough.FastTransform
+	}
+	opts := []hough.Option{hough.Size(accDistances, accAngles)}
+	if *inputThreshold != "" {
+		m, err := thresh.ByName(*inputThreshold)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		gray := gray16.From(baseImage, gray16.Rec601)
+		// The pixels that vote are dark, so the threshold is found from
+		// the negative where they are the light foreground.
+		t := m(thresh.Invert(gray).Histogram(256))
+		fmt.Printf("Input threshold: %d\n", math.MaxUint16-int(t))
+		opts = append(opts, hough.Votes(thresh.Darker(t)))
+	}
+	acc, err := transform(baseImage, opts...)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	if *npzOut != "" {
+		if err := writeFile(*npzOut, func(f *os.File) error { return acc.WriteNPZ(f, hough.Uint16) }); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *csvOut != "" {
+		if err := writeFile(*csvOut, func(f *os.File) error { return acc.WriteCSV(f) }); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *heatmapOut != "" {
+		if err := writeHeatmap(*heatmapOut, acc); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Peaks are found on the filtered accumulator.
+	filtered := hough.Butterfly(acc)
 	outFile, err := os.Create(*out)
 	defer outFile.Close()
 	if err != nil {
 		println(err)
 		os.Exit(1)
 	}
-	acc.Normalise()
-	acc = conv.AdaptiveThresh(acc)
-	png.Encode(outFile, acc)
+	switch *enhanceAcc {
+	case "":
+		filtered.Normalise()
+	case "equalise":
+		// Most buckets hold few or no votes, a plain equalisation spreads
+		// them over the full range where no threshold tells them from the
+		// peaks, so the whole accumulator is equalised as one contrast
+		// limited tile.
+		filtered.Gray16, err = enhance.CLAHE(filtered.Gray16, enhance.Tiles(1, 1))
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	case "clahe":
+		filtered.Gray16, err = enhance.CLAHE(filtered.Gray16)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("unknown enhancement %q\n", *enhanceAcc)
+		os.Exit(1)
+	}
+	var peaks *gray16.Gray16
+	if *threshold == "adaptive" {
+		peaks = conv.AdaptiveThresh(filtered.Gray16)
+	} else {
+		m, err := thresh.ByName(*threshold)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		peaks = thresh.Auto(filtered.Gray16, m, 256)
+	}
+	if err := encode(outFile, *out, peaks); err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	blobs := blob.Find(peaks)
+	var lines []hough.Line
+	for _, b := range blobs {
+		lines = append(lines, acc.LineAt(b.Centre()))
+	}
+	// Neighbouring peaks from the same edge are reported as one line.
+	lines = hough.Merge(lines, *mergeAngle*math.Pi/180, *mergeDistance)
+	for i, l := range lines {
+		fmt.Printf("Line %d: %+v\n", i, l)
+	}
+	if *reportOut != "" {
+		im, err := report.Render(baseImage, acc, lines)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		if err := writeFile(*reportOut, func(f *os.File) error { return png.Encode(f, im) }); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+}
 
-	testOut, err := os.Create("testout.png")
-	defer testOut.Close()
+// writeFile creates the named file and writes it with write.
+func writeFile(name string, write func(*os.File) error) error {
+	f, err := os.Create(name)
 	if err != nil {
-		println(err)
-		os.Exit(1)
+		return err
 	}
-	an := norm.NewNormaliser(0, float64(accAngles), 0, math.Pi)
-	maxDistance := math.Sqrt(float64(baseImage.Bounds().Dx()*baseImage.Bounds().Dx()+baseImage.Bounds().Dy()*baseImage.Bounds().Dy())) / 2.0
-	dn := norm.NewNormaliser(0, float64(accDistances), -maxDistance, maxDistance)
-	ctx := canvas.New()
-	ctx.Color(color.NRGBA{255, 0, 0, 255})
-	blobs := blob.Find(acc)
-	for i, b := range blobs {
-		fmt.Printf("Blob %d centre: %+v\n", i, b.Centre())
-		ctx.Line(dn.Normalise(b.Centre().Y), an.Normalise(b.Centre().X))
-	}
-	ctx.Render(baseImage.(draw.Image))
-	png.Encode(testOut, baseImage)
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encode writes img to w in the format given by the extension of name, a
+// netpbm format for .pbm, .pgm, .ppm and .pnm and png otherwise.
+func encode(w io.Writer, name string, img image.Image) error {
+	switch filepath.Ext(name) {
+	case ".pbm":
+		return netpbm.Encode(w, img, netpbm.As(netpbm.PBM))
+	case ".pgm":
+		return netpbm.Encode(w, img, netpbm.As(netpbm.PGM))
+	case ".ppm":
+		return netpbm.Encode(w, img, netpbm.As(netpbm.PPM))
+	case ".pnm":
+		return netpbm.Encode(w, img)
+	}
+	return png.Encode(w, img)
+}
+
+// writeHeatmap writes the accumulator in false colour to the named png file
+// as configured by the flags.
+func writeHeatmap(name string, acc *hough.Accumulator) error {
+	m, err := heatmap.ByName(*colours)
+	if err != nil {
+		return err
+	}
+	s, err := heatmap.ParseScale(*scale)
+	if err != nil {
+		return err
+	}
+	im, err := heatmap.RenderAccumulator(acc, heatmap.Colours(m), heatmap.Scaling(s), heatmap.Ticks(), heatmap.Bar())
+	if err != nil {
+		return err
+	}
+	return writeFile(name, func(f *os.File) error { return png.Encode(f, im) })
 }
 
 type Line interface {
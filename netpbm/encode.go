@@ -0,0 +1,164 @@
+package netpbm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// Option configures Encode.
+type Option func(*config)
+
+type config struct {
+	format Format
+	plain  bool
+	maxVal int
+}
+
+// As sets the format to write. The default is PGM for gray images and PPM
+// otherwise.
+func As(f Format) Option {
+	return func(c *config) {
+		c.format = f
+	}
+}
+
+// Plain writes the plain ASCII form, P1 to P3, rather than the raw binary
+// form.
+func Plain() Option {
+	return func(c *config) {
+		c.plain = true
+	}
+}
+
+// MaxValue sets the maximum value of the samples of a graymap or pixmap, from
+// 1 to 65535. The default is 65535 for images of 16 bits per channel and 255
+// otherwise. It is ignored for bitmaps.
+func MaxValue(v int) Option {
+	return func(c *config) {
+		c.maxVal = v
+	}
+}
+
+// Encode writes img to w in a netpbm format. Gray values are found as by
+// color.Gray16Model, bitmaps are black where they are below half and colours
+// are written with their alpha premultiplied, as if over black.
+func Encode(w io.Writer, img image.Image, opts ...Option) error {
+	c := config{maxVal: math.MaxUint8}
+	switch img.(type) {
+	case *gray16.Gray16, *image.Gray16:
+		c.format, c.maxVal = PGM, math.MaxUint16
+	case *image.Gray:
+		c.format = PGM
+	case *image.RGBA64, *image.NRGBA64:
+		c.format, c.maxVal = PPM, math.MaxUint16
+	default:
+		c.format = PPM
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	if c.format < PBM || c.format > PPM {
+		return fmt.Errorf("netpbm: invalid format %d", c.format)
+	}
+	if c.format == PBM {
+		c.maxVal = 1
+	}
+	if c.maxVal < 1 || c.maxVal > math.MaxUint16 {
+		return fmt.Errorf("netpbm: invalid maximum value %d", c.maxVal)
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return fmt.Errorf("netpbm: cannot encode an empty image")
+	}
+
+	// The samples in raster order, scaled to the maximum value.
+	var values []uint16
+	scale := func(v uint32) uint16 {
+		return uint16((v*uint32(c.maxVal) + math.MaxUint16/2) / math.MaxUint16)
+	}
+	if c.format == PPM {
+		values = make([]uint16, 0, 3*b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, _ := img.At(x, y).RGBA()
+				values = append(values, scale(r), scale(g), scale(bl))
+			}
+		}
+	} else {
+		g := gray16.From(img, gray16.Rec601)
+		values = make([]uint16, len(g.Pix))
+		for i, v := range g.Pix {
+			if c.format == PBM {
+				// In a bitmap 1 is black.
+				if v < 1<<15 {
+					values[i] = 1
+				}
+			} else {
+				values[i] = scale(uint32(v))
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	magic := int(c.format)
+	if !c.plain {
+		magic += 3
+	}
+	fmt.Fprintf(bw, "P%d\n%d %d\n", magic, b.Dx(), b.Dy())
+	if c.format != PBM {
+		fmt.Fprintf(bw, "%d\n", c.maxVal)
+	}
+	switch {
+	case c.plain:
+		writePlain(bw, values, b.Dy())
+	case c.format == PBM:
+		row := make([]byte, (b.Dx()+7)/8)
+		for y := 0; y < b.Dy(); y++ {
+			for i := range row {
+				row[i] = 0
+			}
+			for x, v := range values[y*b.Dx() : (y+1)*b.Dx()] {
+				row[x/8] |= byte(v) << (7 - uint(x%8))
+			}
+			bw.Write(row)
+		}
+	case c.maxVal > math.MaxUint8:
+		for _, v := range values {
+			bw.WriteByte(byte(v >> 8))
+			bw.WriteByte(byte(v))
+		}
+	default:
+		for _, v := range values {
+			bw.WriteByte(byte(v))
+		}
+	}
+	return bw.Flush()
+}
+
+// writePlain writes the samples in decimal, starting a new line for each row
+// of the image and keeping lines to at most 70 characters.
+func writePlain(w *bufio.Writer, values []uint16, rows int) {
+	perRow := len(values) / rows
+	line := 0
+	var buf []byte
+	for i, v := range values {
+		buf = strconv.AppendUint(buf[:0], uint64(v), 10)
+		if line > 0 && (i%perRow == 0 || line+1+len(buf) > 70) {
+			w.WriteByte('\n')
+			line = 0
+		}
+		if line > 0 {
+			w.WriteByte(' ')
+			line++
+		}
+		w.Write(buf)
+		line += len(buf)
+	}
+	w.WriteByte('\n')
+}
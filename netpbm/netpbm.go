@@ -0,0 +1,329 @@
+// Package netpbm reads and writes the netpbm image formats, PBM, PGM and PPM
+// in both their plain (ASCII) and raw (binary) forms, P1 to P6, with maximum
+// values up to 65535.
+//
+// Bitmaps and graymaps are decoded into a *gray16.Gray16, with the values
+// scaled from the maximum value of the file to the full 16 bit range, and
+// pixmaps into an *image.RGBA, or an *image.RGBA64 if their maximum value is
+// above 255. Importing the package registers the formats with the image
+// package, so that image.Decode reads them.
+package netpbm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+func init() {
+	for magic, name := range map[string]string{
+		"P1": "pbm", "P4": "pbm",
+		"P2": "pgm", "P5": "pgm",
+		"P3": "ppm", "P6": "ppm",
+	} {
+		image.RegisterFormat(name, magic, Decode, DecodeConfig)
+	}
+}
+
+// Format is a netpbm image type.
+type Format int
+
+const (
+	// PBM is a bitmap of black and white pixels, P1 or P4.
+	PBM Format = iota + 1
+	// PGM is a graymap, P2 or P5.
+	PGM
+	// PPM is a pixmap of red, green and blue, P3 or P6.
+	PPM
+)
+
+// maxPixels is the largest number of pixels of an image that is decoded,
+// about 67 megapixels.
+const maxPixels = 1 << 26
+
+// header holds the fields that precede the raster of a netpbm image.
+type header struct {
+	format        Format
+	plain         bool
+	width, height int
+	maxVal        int
+}
+
+// readHeader reads the header of an image up to and including the single
+// whitespace character that precedes the raster.
+func readHeader(r *bufio.Reader) (header, error) {
+	var h header
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return h, fmt.Errorf("netpbm: reading magic number: %v", err)
+	}
+	if magic[0] != 'P' || magic[1] < '1' || magic[1] > '6' {
+		return h, fmt.Errorf("netpbm: invalid magic number %q", magic)
+	}
+	n := int(magic[1] - '0')
+	h.plain = n <= 3
+	h.format = Format((n-1)%3 + 1)
+	fields := []*int{&h.width, &h.height}
+	if h.format != PBM {
+		fields = append(fields, &h.maxVal)
+	} else {
+		h.maxVal = 1
+	}
+	for _, f := range fields {
+		v, err := readInt(r)
+		if err != nil {
+			return h, err
+		}
+		*f = v
+	}
+	if h.width < 1 || h.height < 1 {
+		return h, fmt.Errorf("netpbm: invalid size %dx%d", h.width, h.height)
+	}
+	// The raster is allocated before it is read, so a header alone must not
+	// be able to ask for more memory than a reasonable image needs.
+	if int64(h.width)*int64(h.height) > maxPixels {
+		return h, fmt.Errorf("netpbm: image of %dx%d pixels is too large", h.width, h.height)
+	}
+	if h.maxVal < 1 || h.maxVal > math.MaxUint16 {
+		return h, fmt.Errorf("netpbm: invalid maximum value %d", h.maxVal)
+	}
+	// A single whitespace character separates the header from the raster.
+	c, err := r.ReadByte()
+	if err != nil {
+		return h, fmt.Errorf("netpbm: reading header: %v", err)
+	}
+	if !isSpace(c) {
+		return h, fmt.Errorf("netpbm: expecting whitespace after the header got %q", c)
+	}
+	return h, nil
+}
+
+// readInt skips whitespace and comments and reads a decimal integer.
+func readInt(r *bufio.Reader) (int, error) {
+	c, err := skip(r)
+	if err != nil {
+		return 0, err
+	}
+	if c < '0' || c > '9' {
+		return 0, fmt.Errorf("netpbm: expecting a number got %q", c)
+	}
+	v := 0
+	for c >= '0' && c <= '9' {
+		v = v*10 + int(c-'0')
+		if v > math.MaxInt32 {
+			return 0, errors.New("netpbm: number too large")
+		}
+		c, err = r.ReadByte()
+		if err == io.EOF {
+			return v, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("netpbm: %v", err)
+		}
+	}
+	return v, r.UnreadByte()
+}
+
+// skip skips whitespace and comments, which run from # to the end of the
+// line, and returns the next character.
+func skip(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("netpbm: unexpected end of data: %v", err)
+		}
+		switch {
+		case c == '#':
+			if _, err := r.ReadBytes('\n'); err != nil {
+				return 0, fmt.Errorf("netpbm: unexpected end of data: %v", err)
+			}
+		case !isSpace(c):
+			return c, nil
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// DecodeConfig returns the color model and dimensions of a netpbm image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: h.colorModel(), Width: h.width, Height: h.height}, nil
+}
+
+func (h header) colorModel() color.Model {
+	switch {
+	case h.format != PPM:
+		return color.Gray16Model
+	case h.maxVal > math.MaxUint8:
+		return color.RGBA64Model
+	}
+	return color.RGBAModel
+}
+
+// Decode reads a netpbm image from r.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	samples := h.width * h.height
+	if h.format == PPM {
+		samples *= 3
+	}
+	// The samples are appended as they are read, so that a header claiming
+	// a large image does not allocate for it before the raster is found to
+	// be short.
+	var values []uint16
+	switch {
+	case h.format == PBM && h.plain:
+		values, err = readPlainBits(br, samples)
+	case h.format == PBM:
+		values, err = readRawBits(br, samples, h.width)
+	case h.plain:
+		values, err = readPlain(br, samples, h.maxVal)
+	default:
+		values, err = readRaw(br, samples, h.maxVal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rect := image.Rect(0, 0, h.width, h.height)
+	scale := func(v uint16) uint16 {
+		return uint16((uint32(v)*math.MaxUint16 + uint32(h.maxVal)/2) / uint32(h.maxVal))
+	}
+	switch {
+	case h.format == PBM:
+		// In a bitmap 1 is black.
+		g := gray16.NewGray16(rect)
+		for i, v := range values {
+			g.Pix[i] = (1 - v) * math.MaxUint16
+		}
+		g.UpdateRange()
+		return g, nil
+	case h.format == PGM:
+		g := gray16.NewGray16(rect)
+		for i, v := range values {
+			g.Pix[i] = scale(v)
+		}
+		g.UpdateRange()
+		return g, nil
+	case h.maxVal > math.MaxUint8:
+		m := image.NewRGBA64(rect)
+		for i := 0; i < len(values); i += 3 {
+			j := i / 3 * 8
+			for k := 0; k < 3; k++ {
+				v := scale(values[i+k])
+				m.Pix[j+2*k], m.Pix[j+2*k+1] = uint8(v>>8), uint8(v)
+			}
+			m.Pix[j+6], m.Pix[j+7] = 0xff, 0xff
+		}
+		return m, nil
+	}
+	m := image.NewRGBA(rect)
+	for i := 0; i < len(values); i += 3 {
+		j := i / 3 * 4
+		for k := 0; k < 3; k++ {
+			m.Pix[j+k] = uint8(scale(values[i+k]) >> 8)
+		}
+		m.Pix[j+3] = 0xff
+	}
+	return m, nil
+}
+
+// chunk is the number of samples of a raw raster read at a time.
+const chunk = 1 << 16
+
+// readPlainBits reads the n bits of a plain bitmap, which need not be
+// separated by whitespace.
+func readPlainBits(r *bufio.Reader, n int) ([]uint16, error) {
+	var values []uint16
+	for i := 0; i < n; i++ {
+		c, err := skip(r)
+		if err != nil {
+			return nil, err
+		}
+		if c != '0' && c != '1' {
+			return nil, fmt.Errorf("netpbm: invalid bit %q", c)
+		}
+		values = append(values, uint16(c-'0'))
+	}
+	return values, nil
+}
+
+// readRawBits reads the n bits of a raw bitmap, each row is packed most
+// significant bit first and padded to a whole byte.
+func readRawBits(r io.Reader, n, width int) ([]uint16, error) {
+	var values []uint16
+	row := make([]byte, (width+7)/8)
+	for y := 0; y < n/width; y++ {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, fmt.Errorf("netpbm: reading raster: %v", err)
+		}
+		for x := 0; x < width; x++ {
+			values = append(values, uint16(row[x/8]>>(7-uint(x%8)))&1)
+		}
+	}
+	return values, nil
+}
+
+// readPlain reads the n decimal samples of a plain graymap or pixmap.
+func readPlain(r *bufio.Reader, n, maxVal int) ([]uint16, error) {
+	var values []uint16
+	for i := 0; i < n; i++ {
+		v, err := readInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if v > maxVal {
+			return nil, fmt.Errorf("netpbm: sample %d is above the maximum value %d", v, maxVal)
+		}
+		values = append(values, uint16(v))
+	}
+	return values, nil
+}
+
+// readRaw reads the n binary samples of a raw graymap or pixmap, one byte
+// each if the maximum value is below 256 and two bytes, most significant
+// first, otherwise. They are read a chunk at a time.
+func readRaw(r io.Reader, n, maxVal int) ([]uint16, error) {
+	size := 1
+	if maxVal > math.MaxUint8 {
+		size = 2
+	}
+	var values []uint16
+	buf := make([]byte, chunk*size)
+	for len(values) < n {
+		m := n - len(values)
+		if m > chunk {
+			m = chunk
+		}
+		if _, err := io.ReadFull(r, buf[:m*size]); err != nil {
+			return nil, fmt.Errorf("netpbm: reading raster: %v", err)
+		}
+		for i := 0; i < m; i++ {
+			v := uint16(buf[i])
+			if size == 2 {
+				v = uint16(buf[2*i])<<8 | uint16(buf[2*i+1])
+			}
+			if int(v) > maxVal {
+				return nil, fmt.Errorf("netpbm: sample %d is above the maximum value %d", v, maxVal)
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
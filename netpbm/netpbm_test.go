@@ -0,0 +1,148 @@
+package netpbm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+func TestDecodePlain(t *testing.T) {
+	for _, c := range []struct {
+		name, data string
+		expected   []uint16
+	}{
+		{"bitmap", "P1\n# a comment\n3 2\n010\n1 1 0\n", []uint16{math.MaxUint16, 0, math.MaxUint16, 0, 0, math.MaxUint16}},
+		{"graymap", "P2 3 1 # width height\n4\n0 2 4", []uint16{0, 32768, math.MaxUint16}},
+		{"raw bitmap", "P4 10 1\n\x80\x40", []uint16{0, 65535, 65535, 65535, 65535, 65535, 65535, 65535, 65535, 0}},
+		{"16 bit", "P5 2 1 65535\n\x12\x34\xff\xfe", []uint16{0x1234, 0xfffe}},
+	} {
+		img, format, err := image.Decode(strings.NewReader(c.data))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if format != "pbm" && format != "pgm" {
+			t.Errorf("%s: unexpected format %q", c.name, format)
+		}
+		g, ok := img.(*gray16.Gray16)
+		if !ok {
+			t.Errorf("%s: expecting a gray16 image got %T", c.name, img)
+			continue
+		}
+		for i, e := range c.expected {
+			if g.Pix[i] != e {
+				t.Errorf("%s: expecting %d at %d got %d", c.name, e, i, g.Pix[i])
+			}
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"magic":     "P7 1 1 1\n\x00",
+		"size":      "P5 0 1 255\n",
+		"max value": "P5 1 1 70000\n\x00\x00",
+		"sample":    "P2 1 1 3\n4\n",
+		"short":     "P6 2 2 255\n\x00\x00\x00",
+		"bit":       "P1 1 1\n2\n",
+		"too large": "P5 2147483647 2147483647 255\n",
+		"too many":  "P4 16384 16384\n",
+	} {
+		if _, err := Decode(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: expecting an error", name)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	// A header claiming about 400MB of samples followed by almost none must
+	// fail without allocating for the claimed size.
+	data := "P6 8192 8192 65535\n" + strings.Repeat("\x00", 100)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := Decode(strings.NewReader(data)); err == nil {
+		t.Fatal("Expecting an error for a truncated raster")
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("Expecting little to be allocated got %d bytes", allocated)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(2, 3, 7, 5))
+	for i := range g.Pix {
+		g.Pix[i] = uint16(i * 6000)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 40, 3))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i * 7)
+		if i%4 == 3 {
+			rgba.Pix[i] = 0xff
+		}
+	}
+	rgba64 := image.NewRGBA64(image.Rect(0, 0, 2, 2))
+	rgba64.SetRGBA64(1, 1, color.RGBA64{0x1234, 0x5678, 0x9abc, 0xffff})
+
+	for _, plain := range []bool{false, true} {
+		var opts []Option
+		if plain {
+			opts = append(opts, Plain())
+		}
+		for name, img := range map[string]image.Image{"gray16": g, "rgba": rgba, "rgba64": rgba64} {
+			var buf bytes.Buffer
+			if err := Encode(&buf, img, opts...); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if plain {
+				for _, line := range strings.Split(buf.String(), "\n") {
+					if len(line) > 70 {
+						t.Errorf("%s: line of %d characters", name, len(line))
+					}
+				}
+			}
+			cfg, err := DecodeConfig(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			b := img.Bounds()
+			if cfg.Width != b.Dx() || cfg.Height != b.Dy() {
+				t.Errorf("%s: expecting %dx%d got %dx%d", name, b.Dx(), b.Dy(), cfg.Width, cfg.Height)
+			}
+			out, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					r0, g0, b0, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+					r1, g1, b1, _ := out.At(x, y).RGBA()
+					if r0 != r1 || g0 != g1 || b0 != b1 {
+						t.Fatalf("%s plain %t: expecting %d,%d,%d at (%d, %d) got %d,%d,%d", name, plain, r0, g0, b0, x, y, r1, g1, b1)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeBitmap(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(0, 0, 9, 1))
+	g.Pix[8] = math.MaxUint16
+	var buf bytes.Buffer
+	if err := Encode(&buf, g, As(PBM)); err != nil {
+		t.Fatal(err)
+	}
+	if e := "P4\n9 1\n\xff\x00"; buf.String() != e {
+		t.Errorf("Expecting %q got %q", e, buf.String())
+	}
+	if err := Encode(&buf, g, MaxValue(0)); err == nil {
+		t.Error("Expecting an error for a maximum value of 0")
+	}
+}
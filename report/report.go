@@ -0,0 +1,156 @@
+// Package report renders annotated images of the lines detected by a hough
+// transform, for checking detections by eye.
+package report
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/piersy/hough-go/canvas"
+	"github.com/piersy/hough-go/heatmap"
+	"github.com/piersy/hough-go/hough"
+)
+
+// Palette holds the colours given to the lines in turn, it is the tab10
+// palette of matplotlib whose colours are easy to tell apart.
+var Palette = []color.RGBA{
+	{31, 119, 180, 255}, {255, 127, 14, 255}, {44, 160, 44, 255},
+	{214, 39, 40, 255}, {148, 103, 189, 255}, {140, 86, 75, 255},
+	{227, 119, 194, 255}, {127, 127, 127, 255}, {188, 189, 34, 255},
+	{23, 190, 207, 255},
+}
+
+// Sizes, in pixels, of the parts of the report.
+const (
+	margin     = 8
+	crosshair  = 6
+	legendLine = canvas.GlyphHeight + 4
+)
+
+// Render returns a report of the lines found in the accumulator acc of the
+// input image. On the left is the input with each line drawn in its own
+// colour and labelled with its index. On the right is the accumulator as a
+// heatmap with the peak of each line marked by a crosshair of the same
+// colour. Below them a legend lists the distance, angle and votes of each
+// line.
+func Render(input image.Image, acc *hough.Accumulator, lines []hough.Line) (*image.RGBA, error) {
+	if input == nil {
+		return nil, errors.New("report: nil input image")
+	}
+	if acc == nil {
+		return nil, errors.New("report: nil accumulator")
+	}
+	heat, err := heatmap.RenderAccumulator(acc, heatmap.Scaling(heatmap.Log), heatmap.Colours(heatmap.Grayscale))
+	if err != nil {
+		return nil, err
+	}
+
+	legend := make([]string, len(lines))
+	legendWidth := 0
+	for i, l := range lines {
+		legend[i] = fmt.Sprintf("%d: rho %.1f theta %.2f° votes %.0f", i, l.Distance, l.Angle*180/math.Pi, l.Votes)
+		legendWidth = maxInt(legendWidth, canvas.TextWidth(legend[i])+legendLine+2)
+	}
+	in := input.Bounds()
+	panels := in.Dx() + margin + heat.Bounds().Dx()
+	height := maxInt(in.Dy(), heat.Bounds().Dy())
+	out := image.NewRGBA(image.Rect(0, 0,
+		2*margin+maxInt(panels, legendWidth),
+		2*margin+height+len(lines)*legendLine+margin))
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	// The input panel is drawn in the coordinates of the input so that the
+	// lines can be drawn with the ends the accumulator gives.
+	annotated := image.NewRGBA(in)
+	draw.Draw(annotated, in, input, in.Min, draw.Src)
+	ctx := canvas.NewTextContext()
+	for i, l := range lines {
+		from, to, ok := acc.Ends(l)
+		if !ok {
+			continue
+		}
+		ctx.Color(colour(i))
+		ctx.MoveTo(image.Pt(int(from.X), int(from.Y)))
+		ctx.LineTo(image.Pt(int(to.X), int(to.Y)))
+		// Label the middle of the line, a little way off it along its
+		// normal and kept within the panel.
+		sin, cos := math.Sincos(l.Angle)
+		label := fmt.Sprint(i)
+		x := int((from.X+to.X)/2+4*cos) - canvas.TextWidth(label)/2
+		y := int((from.Y+to.Y)/2+4*sin) - canvas.GlyphHeight/2
+		x = clamp(x, in.Min.X, in.Max.X-canvas.TextWidth(label))
+		y = clamp(y, in.Min.Y, in.Max.Y-canvas.GlyphHeight)
+		ctx.Text(image.Pt(x, y), label)
+	}
+	ctx.Render(annotated)
+	draw.Draw(out, image.Rect(margin, margin, margin+in.Dx(), margin+in.Dy()), annotated, in.Min, draw.Src)
+
+	// The accumulator panel, its pixels are the buckets of the accumulator.
+	for i, l := range lines {
+		x, y, ok := peak(acc, l)
+		if !ok {
+			continue
+		}
+		c := colour(i)
+		for d := 2; d <= crosshair; d++ {
+			heat.SetRGBA(x-d, y, c)
+			heat.SetRGBA(x+d, y, c)
+			heat.SetRGBA(x, y-d, c)
+			heat.SetRGBA(x, y+d, c)
+		}
+		label := canvas.NewTextContext()
+		label.Color(c)
+		label.Text(image.Pt(x+crosshair+2, y-crosshair-canvas.GlyphHeight), fmt.Sprint(i))
+		label.Render(heat)
+	}
+	x0 := margin + in.Dx() + margin
+	draw.Draw(out, image.Rect(x0, margin, x0+heat.Bounds().Dx(), margin+heat.Bounds().Dy()), heat, image.Point{}, draw.Src)
+
+	// The legend, each entry has a swatch of the colour of its line.
+	ctx = canvas.NewTextContext()
+	ctx.Color(color.Black)
+	top := 2*margin + height
+	for i, entry := range legend {
+		y := top + i*legendLine
+		draw.Draw(out, image.Rect(margin, y, margin+legendLine-2, y+legendLine-2), image.NewUniform(colour(i)), image.Point{}, draw.Src)
+		ctx.Text(image.Pt(margin+legendLine+2, y+1), entry)
+	}
+	ctx.Render(out)
+	return out, nil
+}
+
+// colour returns the colour of the line with index i.
+func colour(i int) color.RGBA {
+	return Palette[i%len(Palette)]
+}
+
+// peak returns the bucket of the accumulator in which the line lies,
+// relative to its top left corner, ok is false if it lies outside the
+// accumulator.
+func peak(acc *hough.Accumulator, l hough.Line) (x, y int, ok bool) {
+	x = int(math.Floor(acc.AngleBin(l.Angle)))
+	y = int(math.Floor(acc.DistanceBin(l.Distance)))
+	b := acc.Bounds()
+	return x, y, x >= 0 && y >= 0 && x < b.Dx() && y < b.Dy()
+}
+
+func clamp(v, min, max int) int {
+	if v > max {
+		v = max
+	}
+	if v < min {
+		v = min
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package report
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/hough"
+)
+
+func TestRender(t *testing.T) {
+	im := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw.Draw(im, im.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for x := 0; x < 60; x++ {
+		im.Set(x, 30, color.Black)
+	}
+	acc, err := hough.Transform(im, hough.Size(80, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The line y = 30, 10 pixels below the centre of the input.
+	lines := []hough.Line{{Angle: math.Pi / 2, Distance: 10, Votes: 60}}
+	out, err := Render(im, acc, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := out.Bounds()
+	if b.Dx() < 60+90+3*margin || b.Dy() < 80+2*margin+legendLine {
+		t.Fatalf("Expecting room for both panels and the legend got %v", b)
+	}
+	// The line is drawn over the input in its colour.
+	if c := out.RGBAAt(margin+20, margin+30); c != Palette[0] {
+		t.Errorf("Expecting the line in %v got %v", Palette[0], c)
+	}
+	// The peak is marked with a crosshair of the same colour.
+	x, y, ok := peak(acc, lines[0])
+	if !ok {
+		t.Fatal("Expecting the line to lie in the accumulator")
+	}
+	x += margin + 60 + margin
+	y += margin
+	if c := out.RGBAAt(x+crosshair, y); c != Palette[0] {
+		t.Errorf("Expecting a crosshair in %v got %v", Palette[0], c)
+	}
+	// The legend has a swatch for the line.
+	if c := out.RGBAAt(margin+1, 2*margin+80+1); c != Palette[0] {
+		t.Errorf("Expecting a legend swatch in %v got %v", Palette[0], c)
+	}
+}
+
+func TestPeakMerged(t *testing.T) {
+	acc, err := hough.Transform(image.NewRGBA(image.Rect(0, 0, 10, 10)), hough.Size(20, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lines either side of the wrap at Pi merge into a line that lies in
+	// the accumulator.
+	merged := hough.Merge([]hough.Line{
+		{Angle: 0.01, Distance: -3, Votes: 30},
+		{Angle: math.Pi - 0.05, Distance: 3, Votes: 10},
+	}, 0.1, 1)
+	if len(merged) != 1 {
+		t.Fatalf("Expecting 1 line got %+v", merged)
+	}
+	if _, _, ok := peak(acc, merged[0]); !ok {
+		t.Errorf("Expecting %+v to lie in the accumulator", merged[0])
+	}
+}
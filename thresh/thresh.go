@@ -0,0 +1,259 @@
+// Package thresh selects global thresholds for gray16 images automatically
+// from their histograms and binarises images with them.
+package thresh
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/piersy/hough-go/blob"
+	"github.com/piersy/hough-go/edge"
+	"github.com/piersy/hough-go/gray16"
+)
+
+// Method selects a threshold from a histogram. Values above the threshold
+// are foreground and values at or below it background. A histogram whose
+// values cannot be separated gives its highest value, so that nothing is
+// foreground.
+type Method func(h *gray16.Histogram) uint16
+
+// ByName returns the method with the given name, one of otsu, triangle or
+// kapur.
+func ByName(name string) (Method, error) {
+	switch name {
+	case "otsu":
+		return Otsu, nil
+	case "triangle":
+		return Triangle, nil
+	case "kapur":
+		return Kapur, nil
+	}
+	return nil, fmt.Errorf("thresh: unknown method %q", name)
+}
+
+// Binarise returns an image of the bounds of g in which pixels whose value is
+// above t are blob.BlobColor and the rest black, ready for blob.Find.
+func Binarise(g *gray16.Gray16, t uint16) *gray16.Gray16 {
+	out := gray16.NewGray16(g.Rect)
+	b := g.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if g.Pix[g.PixOffset(x, y)] > t {
+				out.SetGray16(x, y, blob.BlobColor)
+			} else {
+				out.SetGray16(x, y, color.Gray16{})
+			}
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+// Auto binarises g with the threshold the method selects from its histogram
+// of the given number of bins, see Gray16.Histogram.
+func Auto(g *gray16.Gray16, m Method, bins int) *gray16.Gray16 {
+	return Binarise(g, m(g.Histogram(bins)))
+}
+
+// Invert returns the negative of g. A method applied to the histogram of the
+// negative finds a threshold for dark foreground on a light background while
+// keeping to the convention that values above the threshold are foreground.
+func Invert(g *gray16.Gray16) *gray16.Gray16 {
+	out := gray16.NewGray16(g.Rect)
+	b := g.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i, o := g.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+		for x, v := range g.Pix[i : i+b.Dx()] {
+			out.Pix[o+x] = math.MaxUint16 - v
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+// Darker returns a predicate that selects the pixels whose negative
+// luminance, as given by color.Gray16Model, is above t, that is those darker
+// than math.MaxUint16-t. It selects the dark foreground of an input image for
+// the hough transform with a threshold found from the histogram of its
+// negative, see Invert.
+func Darker(t uint16) edge.Predicate {
+	return func(r, g, b, a uint32) bool {
+		y := (19595*r + 38470*g + 7471*b + 1<<15) >> 16
+		return math.MaxUint16-y > uint32(t)
+	}
+}
+
+// Otsu selects the threshold that maximises the variance between the
+// background and foreground, which suits histograms with two well separated
+// peaks.
+func Otsu(h *gray16.Histogram) uint16 {
+	total := float64(h.Total())
+	var sum float64
+	for i, c := range h.Counts {
+		sum += float64(i) * float64(c)
+	}
+	// The between class variance of splitting after bin k is proportional
+	// to (mean*w0 - sum0)² / (w0*w1).
+	mean := sum / total
+	best, bestVariance := -1, 0.0
+	var w0, sum0 float64
+	for k, c := range h.Counts[:len(h.Counts)-1] {
+		w0 += float64(c)
+		sum0 += float64(k) * float64(c)
+		w1 := total - w0
+		if w0 == 0 || w1 == 0 {
+			continue
+		}
+		d := mean*w0 - sum0
+		if v := d * d / (w0 * w1); v > bestVariance {
+			best, bestVariance = k, v
+		}
+	}
+	if best < 0 {
+		return h.Max
+	}
+	return h.Upper(best)
+}
+
+// MultiOtsu selects the classes-1 thresholds that divide the histogram into
+// the given number of classes with the greatest variance between them,
+// returned in increasing order. Class i holds the values above threshold i-1
+// and at or below threshold i. Fewer thresholds are returned if the
+// histogram has fewer bins than classes.
+//
+// The thresholds are found exactly by dynamic programming in
+// O(classes * bins²) time, so histograms of a few hundred bins are advised.
+func MultiOtsu(h *gray16.Histogram, classes int) []uint16 {
+	n := len(h.Counts)
+	if classes > n {
+		classes = n
+	}
+	if classes < 2 || h.Total() == 0 {
+		return nil
+	}
+	// Prefix sums of the counts and of the counts weighted by bin, the
+	// between class variance is, up to constants, the sum over the classes
+	// of sum²/count.
+	count := make([]float64, n+1)
+	sum := make([]float64, n+1)
+	for i, c := range h.Counts {
+		count[i+1] = count[i] + float64(c)
+		sum[i+1] = sum[i] + float64(i)*float64(c)
+	}
+	score := func(from, to int) float64 {
+		c := count[to] - count[from]
+		if c == 0 {
+			return 0
+		}
+		s := sum[to] - sum[from]
+		return s * s / c
+	}
+	// best[k][j] is the greatest score of bins [0, j) divided into k+1
+	// classes, and last[k][j] the start of the last class achieving it.
+	best := make([][]float64, classes)
+	last := make([][]int, classes)
+	for k := range best {
+		best[k] = make([]float64, n+1)
+		last[k] = make([]int, n+1)
+		for j := range best[k] {
+			best[k][j] = math.Inf(-1)
+		}
+	}
+	for j := 1; j <= n; j++ {
+		best[0][j] = score(0, j)
+	}
+	for k := 1; k < classes; k++ {
+		for j := k + 1; j <= n; j++ {
+			for i := k; i < j; i++ {
+				if s := best[k-1][i] + score(i, j); s > best[k][j] {
+					best[k][j] = s
+					last[k][j] = i
+				}
+			}
+		}
+	}
+	thresholds := make([]uint16, classes-1)
+	j := n
+	for k := classes - 1; k > 0; k-- {
+		j = last[k][j]
+		thresholds[k-1] = h.Upper(j - 1)
+	}
+	return thresholds
+}
+
+// Triangle selects the threshold by the triangle method of Zack et al. A
+// line is drawn from the peak of the histogram to the end of its tail of
+// high values and the threshold is the bin furthest below it. It suits
+// histograms with a single dominant peak of background, such as a hough
+// accumulator where most buckets hold few votes and lines are rare. For dark
+// foreground apply it to the histogram of the negative image, see Invert.
+func Triangle(h *gray16.Histogram) uint16 {
+	last, peak := -1, 0
+	for i, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		last = i
+		if c > h.Counts[peak] {
+			peak = i
+		}
+	}
+	if last <= peak {
+		return h.Max
+	}
+	// The distance of bin i below the line from (peak, counts[peak]) to
+	// (last, counts[last]) is proportional to dy*(i-peak) - dx*(c-counts[peak]).
+	dx := float64(last - peak)
+	dy := float64(h.Counts[last] - h.Counts[peak])
+	best, bestDistance := peak, 0.0
+	for i := peak; i < last; i++ {
+		d := math.Abs(dy*float64(i-peak) - dx*float64(h.Counts[i]-h.Counts[peak]))
+		if d > bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return h.Upper(best)
+}
+
+// Kapur selects the threshold that maximises the sum of the entropies of the
+// background and foreground histograms, as proposed by Kapur, Sahoo and Wong.
+func Kapur(h *gray16.Histogram) uint16 {
+	total := float64(h.Total())
+	n := len(h.Counts)
+	if total == 0 || n < 2 {
+		return h.Max
+	}
+	// Prefix sums of p and p*ln(p), the entropy of a class with probability
+	// P and sum of p*ln(p) S is ln(P) - S/P.
+	p := make([]float64, n+1)
+	plogp := make([]float64, n+1)
+	for i, c := range h.Counts {
+		q := float64(c) / total
+		p[i+1] = p[i] + q
+		plogp[i+1] = plogp[i]
+		if q > 0 {
+			plogp[i+1] += q * math.Log(q)
+		}
+	}
+	entropy := func(from, to int) float64 {
+		P := p[to] - p[from]
+		if P <= 0 {
+			return 0
+		}
+		return math.Log(P) - (plogp[to]-plogp[from])/P
+	}
+	best, bestEntropy := -1, math.Inf(-1)
+	for k := 1; k < n; k++ {
+		if p[k] <= 0 || p[n]-p[k] <= 0 {
+			continue
+		}
+		if e := entropy(0, k) + entropy(k, n); e > bestEntropy {
+			best, bestEntropy = k, e
+		}
+	}
+	if best < 0 {
+		return h.Max
+	}
+	return h.Upper(best - 1)
+}
@@ -0,0 +1,139 @@
+package thresh
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/blob"
+	"github.com/piersy/hough-go/gray16"
+)
+
+// valuesImage returns an image holding count pixels of each of the values.
+func valuesImage(values map[uint16]int) *gray16.Gray16 {
+	var pix []uint16
+	for v, n := range values {
+		for i := 0; i < n; i++ {
+			pix = append(pix, v)
+		}
+	}
+	g := gray16.NewGray16(image.Rect(0, 0, len(pix), 1))
+	copy(g.Pix, pix)
+	return g
+}
+
+// spread returns count pixels around centre, in a triangle of the given
+// half width.
+func spread(values map[uint16]int, centre, halfWidth, count int) {
+	for d := -halfWidth; d <= halfWidth; d++ {
+		n := count * (halfWidth + 1 - abs(d)) / (halfWidth + 1)
+		values[uint16(centre+d)] += n
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestTwoClasses(t *testing.T) {
+	values := map[uint16]int{}
+	spread(values, 10000, 2000, 50)
+	spread(values, 50000, 2000, 30)
+	g := valuesImage(values)
+	h := g.Histogram(256)
+	for name, m := range map[string]Method{"otsu": Otsu, "kapur": Kapur} {
+		if th := m(h); th <= 11000 || th >= 48000 {
+			t.Errorf("%s: expecting a threshold between the classes got %d", name, th)
+		}
+	}
+	b := Auto(g, Otsu, 256)
+	for x := 0; x < g.Rect.Dx(); x++ {
+		fg := b.Gray16At(x, 0) == blob.BlobColor
+		if fg != (g.Gray16At(x, 0).Y > 30000) {
+			t.Fatalf("Expecting %d to be foreground %t", g.Gray16At(x, 0).Y, !fg)
+		}
+	}
+	if b.MinVal != 0 || b.MaxVal != blob.BlobColor.Y {
+		t.Errorf("Expecting a binary range got %d to %d", b.MinVal, b.MaxVal)
+	}
+}
+
+func TestMultiOtsu(t *testing.T) {
+	values := map[uint16]int{}
+	spread(values, 5000, 1000, 40)
+	spread(values, 30000, 1000, 40)
+	spread(values, 60000, 1000, 40)
+	h := valuesImage(values).Histogram(128)
+	th := MultiOtsu(h, 3)
+	if len(th) != 2 || th[0] <= 6000 || th[0] >= 29000 || th[1] <= 31000 || th[1] >= 59000 {
+		t.Errorf("Expecting thresholds between the three classes got %v", th)
+	}
+	// Two classes agree with Otsu.
+	if th := MultiOtsu(h, 2); len(th) != 1 || th[0] != Otsu(h) {
+		t.Errorf("Expecting two classes to match otsu at %d got %v", Otsu(h), th)
+	}
+}
+
+func TestTriangle(t *testing.T) {
+	// A large peak of low values with a long tail of high values, as in an
+	// accumulator.
+	values := map[uint16]int{}
+	spread(values, 1000, 500, 2000)
+	for v := 1500; v < 20000; v += 50 {
+		values[uint16(v)] += 3
+	}
+	h := valuesImage(values).Histogram(256)
+	if th := Triangle(h); th <= 1000 || th >= 5000 {
+		t.Errorf("Expecting a threshold just above the peak got %d", th)
+	}
+}
+
+func TestDegenerate(t *testing.T) {
+	h := valuesImage(map[uint16]int{700: 10}).Histogram(256)
+	for name, m := range map[string]Method{"otsu": Otsu, "kapur": Kapur, "triangle": Triangle} {
+		if th := m(h); th != 700 {
+			t.Errorf("%s: expecting a constant image to give its value got %d", name, th)
+		}
+	}
+	if th := MultiOtsu(h, 3); len(th) != 0 {
+		t.Errorf("Expecting no thresholds for a constant image got %v", th)
+	}
+}
+
+func TestDarker(t *testing.T) {
+	p := Darker(0x7fff)
+	if !p(0, 0, 0, 0xffff) || p(0xffff, 0xffff, 0xffff, 0xffff) || !p(0x7fff, 0x7fff, 0x7fff, 0xffff) || p(0x8000, 0x8000, 0x8000, 0xffff) {
+		t.Error("Expecting dark pixels to be selected and light ones not")
+	}
+	if p := Darker(math.MaxUint16); p(0, 0, 0, 0xffff) {
+		t.Error("Expecting nothing to be selected by the highest threshold")
+	}
+}
+
+func TestTriangleDarkForeground(t *testing.T) {
+	// A large peak of light background with a long tail of darker values,
+	// as on a page of text.
+	g := gray16.NewGray16(image.Rect(0, 0, 100, 100))
+	for i := range g.Pix {
+		g.Pix[i] = 60000
+		if i%10 == 0 {
+			g.Pix[i] = uint16(i / 10 * 50)
+		}
+	}
+	g.UpdateRange()
+	// The threshold of the image itself keeps to the convention that values
+	// above it are foreground, so the background is not selected as the
+	// foreground of the tail below the peak.
+	if th := Triangle(g.Histogram(256)); th < 60000 {
+		t.Errorf("Expecting no foreground below the peak got a threshold of %d", th)
+	}
+	// The negative finds the dark tail.
+	th := Triangle(Invert(g).Histogram(256))
+	p := Darker(th)
+	if !p(1000, 1000, 1000, 0xffff) || p(60000, 60000, 60000, 0xffff) {
+		t.Errorf("Expecting the dark tail to be selected with a threshold of %d", th)
+	}
+}
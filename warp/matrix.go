@@ -0,0 +1,52 @@
+package warp
+
+import "math"
+
+// Matrix is an affine transform mapping the point (x, y) to
+// (m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]). Coordinates are those of
+// the image plane, the pixel at (i, j) covering the unit square from (i, j)
+// to (i+1, j+1).
+type Matrix [6]float64
+
+// Identity is the transform that leaves points unchanged.
+var Identity = Matrix{1, 0, 0, 0, 1, 0}
+
+// Translate returns the transform that moves points by (dx, dy).
+func Translate(dx, dy float64) Matrix {
+	return Matrix{1, 0, dx, 0, 1, dy}
+}
+
+// Scale returns the transform that scales points about the origin.
+func Scale(sx, sy float64) Matrix {
+	return Matrix{sx, 0, 0, 0, sy, 0}
+}
+
+// Rotation returns the transform that rotates points about the origin by
+// angle radians, clockwise as displayed since y increases down the image.
+func Rotation(angle float64) Matrix {
+	sin, cos := math.Sincos(angle)
+	return Matrix{cos, -sin, 0, sin, cos, 0}
+}
+
+// Mul returns the transform that applies n and then m.
+func (m Matrix) Mul(n Matrix) Matrix {
+	return Matrix{
+		m[0]*n[0] + m[1]*n[3], m[0]*n[1] + m[1]*n[4], m[0]*n[2] + m[1]*n[5] + m[2],
+		m[3]*n[0] + m[4]*n[3], m[3]*n[1] + m[4]*n[4], m[3]*n[2] + m[4]*n[5] + m[5],
+	}
+}
+
+// Apply returns the point (x, y) transformed by m.
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]
+}
+
+// Invert returns the inverse of m, ok is false if m is singular.
+func (m Matrix) Invert() (inv Matrix, ok bool) {
+	det := m[0]*m[4] - m[1]*m[3]
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return Matrix{}, false
+	}
+	a, b, d, e := m[4]/det, -m[1]/det, -m[3]/det, m[0]/det
+	return Matrix{a, b, -(a*m[2] + b*m[5]), d, e, -(d*m[2] + e*m[5])}, true
+}
@@ -0,0 +1,389 @@
+// Package warp resizes, flips, rotates and applies general affine transforms
+// to gray16 images.
+package warp
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// Interpolation selects how values are sampled between pixels.
+type Interpolation int
+
+const (
+	// Nearest takes the value of the nearest pixel.
+	Nearest Interpolation = iota
+	// Bilinear interpolates linearly between the four nearest pixels.
+	Bilinear
+	// Bicubic interpolates between the sixteen nearest pixels with the Keys
+	// cubic kernel, giving sharper results than Bilinear with slight
+	// overshoot at edges.
+	Bicubic
+	// Area averages the pixels covered by each output pixel, weighted by
+	// the area covered, which avoids aliasing when downscaling. It is only
+	// supported by Resize.
+	Area
+)
+
+// Border selects the values of pixels sampled from beyond the edges of the
+// source image.
+type Border int
+
+const (
+	// Constant takes the fill value, see Fill.
+	Constant Border = iota
+	// Replicate repeats the nearest edge pixel.
+	Replicate
+	// Reflect mirrors the image about its edges, so the pixels beyond an
+	// edge repeat those inside it in reverse order.
+	Reflect
+	// Wrap tiles the image.
+	Wrap
+)
+
+// Option configures a transform.
+type Option func(*config)
+
+type config struct {
+	interpolation Interpolation
+	border        Border
+	fill          uint16
+}
+
+// Interpolate sets the interpolation, the default is Bilinear.
+func Interpolate(i Interpolation) Option {
+	return func(c *config) {
+		c.interpolation = i
+	}
+}
+
+// Borders sets how pixels beyond the edges of the source are sampled, the
+// default is Constant.
+func Borders(b Border) Option {
+	return func(c *config) {
+		c.border = b
+	}
+}
+
+// Fill sets the value of pixels beyond the edges of the source for the
+// Constant border, the default is 0.
+func Fill(v uint16) Option {
+	return func(c *config) {
+		c.fill = v
+	}
+}
+
+func newConfig(g *gray16.Gray16, opts []Option) (config, error) {
+	c := config{interpolation: Bilinear}
+	for _, o := range opts {
+		o(&c)
+	}
+	if g == nil {
+		return c, errors.New("warp: nil image")
+	}
+	if g.Rect.Empty() {
+		return c, errors.New("warp: empty image")
+	}
+	if c.interpolation < Nearest || c.interpolation > Area {
+		return c, fmt.Errorf("warp: invalid interpolation %d", c.interpolation)
+	}
+	if c.border < Constant || c.border > Wrap {
+		return c, fmt.Errorf("warp: invalid border %d", c.border)
+	}
+	return c, nil
+}
+
+// Resize returns g scaled to width by height pixels, with bounds at the
+// origin. Nearest, Bilinear and Bicubic sample the source at the centre of
+// each output pixel and so alias when downscaling by large factors, Area
+// avoids this. The border defaults to Replicate so that the edges of the
+// result are not blended with the fill.
+func Resize(g *gray16.Gray16, width, height int, opts ...Option) (*gray16.Gray16, error) {
+	c, err := newConfig(g, append([]Option{Borders(Replicate)}, opts...))
+	if err != nil {
+		return nil, err
+	}
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("warp: invalid size %dx%d", width, height)
+	}
+	b := g.Rect
+	if c.interpolation == Area {
+		return resizeArea(g, width, height), nil
+	}
+	m := Scale(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy())).
+		Mul(Translate(-float64(b.Min.X), -float64(b.Min.Y)))
+	return warp(g, m, image.Rect(0, 0, width, height), c)
+}
+
+// resizeArea resizes g by averaging the source pixels covered by each output
+// pixel. The weights are separable, the product of the overlap along each
+// axis.
+func resizeArea(g *gray16.Gray16, width, height int) *gray16.Gray16 {
+	b := g.Rect
+	xs := areaWeights(b.Dx(), width)
+	ys := areaWeights(b.Dy(), height)
+	out := gray16.NewGray16(image.Rect(0, 0, width, height))
+	for y, wy := range ys {
+		for x, wx := range xs {
+			var sum float64
+			for _, j := range wy {
+				row := g.PixOffset(b.Min.X, b.Min.Y+j.index)
+				var s float64
+				for _, i := range wx {
+					s += i.weight * float64(g.Pix[row+i.index])
+				}
+				sum += j.weight * s
+			}
+			out.Pix[out.PixOffset(x, y)] = saturate(sum)
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+type weight struct {
+	index  int
+	weight float64
+}
+
+// areaWeights returns, for each of the n output pixels along an axis, the
+// source pixels of the size along it that it covers and the fraction of the
+// output pixel covered by each.
+func areaWeights(size, n int) [][]weight {
+	scale := float64(size) / float64(n)
+	weights := make([][]weight, n)
+	for o := range weights {
+		from, to := float64(o)*scale, float64(o+1)*scale
+		for i := int(from); i < size && float64(i) < to; i++ {
+			overlap := math.Min(to, float64(i+1)) - math.Max(from, float64(i))
+			if overlap > 0 {
+				weights[o] = append(weights[o], weight{i, overlap / scale})
+			}
+		}
+	}
+	return weights
+}
+
+// FlipHorizontal returns g mirrored left to right.
+func FlipHorizontal(g *gray16.Gray16) *gray16.Gray16 {
+	b := g.Rect
+	out := gray16.NewGray16(b)
+	w := b.Dx()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i, o := g.PixOffset(b.Min.X, y), out.PixOffset(b.Min.X, y)
+		for x, v := range g.Pix[i : i+w] {
+			out.Pix[o+w-1-x] = v
+		}
+	}
+	out.UpdateRange()
+	return out
+}
+
+// FlipVertical returns g mirrored top to bottom.
+func FlipVertical(g *gray16.Gray16) *gray16.Gray16 {
+	b := g.Rect
+	out := gray16.NewGray16(b)
+	w := b.Dx()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := g.PixOffset(b.Min.X, y)
+		copy(out.Pix[out.PixOffset(b.Min.X, b.Max.Y-1-(y-b.Min.Y)):], g.Pix[i:i+w])
+	}
+	out.UpdateRange()
+	return out
+}
+
+// Transpose returns g mirrored about its diagonal, the pixel at (x, y)
+// moving to (y, x), so the bounds are transposed too.
+func Transpose(g *gray16.Gray16) *gray16.Gray16 {
+	b := g.Rect
+	out := gray16.NewGray16(image.Rect(b.Min.Y, b.Min.X, b.Max.Y, b.Max.X))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := g.PixOffset(b.Min.X, y)
+		for x, v := range g.Pix[i : i+b.Dx()] {
+			out.Pix[out.PixOffset(y, b.Min.X+x)] = v
+		}
+	}
+	out.MinVal, out.MaxVal = g.MinVal, g.MaxVal
+	return out
+}
+
+// Size selects the bounds of a rotated image.
+type Size int
+
+const (
+	// Crop keeps the bounds of the source, cutting off the corners of the
+	// rotated image.
+	Crop Size = iota
+	// Expand enlarges the bounds, at the origin, to hold the whole rotated
+	// image.
+	Expand
+)
+
+// Rotate returns g rotated about its centre by angle radians, clockwise as
+// displayed.
+func Rotate(g *gray16.Gray16, angle float64, size Size, opts ...Option) (*gray16.Gray16, error) {
+	c, err := newConfig(g, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.interpolation == Area {
+		return nil, errors.New("warp: area interpolation is only supported by Resize")
+	}
+	b := g.Rect
+	cx, cy := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+	bounds, ox, oy := b, cx, cy
+	switch size {
+	case Crop:
+	case Expand:
+		sin, cos := math.Sincos(angle)
+		w := math.Abs(cos)*float64(b.Dx()) + math.Abs(sin)*float64(b.Dy())
+		h := math.Abs(sin)*float64(b.Dx()) + math.Abs(cos)*float64(b.Dy())
+		// Round away the error of the trigonometry before taking the
+		// ceiling so that right angles give exact sizes.
+		bounds = image.Rect(0, 0, int(math.Ceil(w-1e-9)), int(math.Ceil(h-1e-9)))
+		ox, oy = float64(bounds.Dx())/2, float64(bounds.Dy())/2
+	default:
+		return nil, fmt.Errorf("warp: invalid size %d", size)
+	}
+	m := Translate(ox, oy).Mul(Rotation(angle)).Mul(Translate(-cx, -cy))
+	return warp(g, m, bounds, c)
+}
+
+// Affine returns the image of the given bounds of g transformed by m, which
+// maps points of g to points of the result.
+func Affine(g *gray16.Gray16, m Matrix, bounds image.Rectangle, opts ...Option) (*gray16.Gray16, error) {
+	c, err := newConfig(g, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.interpolation == Area {
+		return nil, errors.New("warp: area interpolation is only supported by Resize")
+	}
+	return warp(g, m, bounds, c)
+}
+
+// warp samples g at the point mapped by the inverse of m from the centre of
+// each pixel of the given bounds.
+func warp(g *gray16.Gray16, m Matrix, bounds image.Rectangle, c config) (*gray16.Gray16, error) {
+	inv, ok := m.Invert()
+	if !ok {
+		return nil, errors.New("warp: singular transform")
+	}
+	s := sampler{g: g, config: c}
+	out := gray16.NewGray16(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		o := out.PixOffset(bounds.Min.X, y)
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sx, sy := inv.Apply(float64(x)+0.5, float64(y)+0.5)
+			out.Pix[o+x-bounds.Min.X] = s.sample(sx, sy)
+		}
+	}
+	out.UpdateRange()
+	return out, nil
+}
+
+// sampler samples an image between its pixels.
+type sampler struct {
+	g *gray16.Gray16
+	config
+}
+
+// sample returns the value at the point (x, y) of the image plane.
+func (s sampler) sample(x, y float64) uint16 {
+	// Relative to the pixel centres.
+	x, y = x-0.5, y-0.5
+	switch s.interpolation {
+	case Nearest:
+		return s.at(int(math.Floor(x+0.5)), int(math.Floor(y+0.5)))
+	case Bicubic:
+		x0, y0 := math.Floor(x), math.Floor(y)
+		var wx, wy [4]float64
+		for k := range wx {
+			wx[k] = cubic(x - x0 - float64(k-1))
+			wy[k] = cubic(y - y0 - float64(k-1))
+		}
+		var sum float64
+		for j, wj := range wy {
+			var row float64
+			for i, wi := range wx {
+				row += wi * float64(s.at(int(x0)+i-1, int(y0)+j-1))
+			}
+			sum += wj * row
+		}
+		return saturate(sum)
+	}
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	i, j := int(x0), int(y0)
+	top := float64(s.at(i, j))*(1-fx) + float64(s.at(i+1, j))*fx
+	bottom := float64(s.at(i, j+1))*(1-fx) + float64(s.at(i+1, j+1))*fx
+	return saturate(top*(1-fy) + bottom*fy)
+}
+
+// at returns the pixel at (x, y), applying the border beyond the edges.
+func (s sampler) at(x, y int) uint16 {
+	b := s.g.Rect
+	if !(image.Point{x, y}.In(b)) {
+		if s.border == Constant {
+			return s.fill
+		}
+		x = b.Min.X + s.fold(x-b.Min.X, b.Dx())
+		y = b.Min.Y + s.fold(y-b.Min.Y, b.Dy())
+	}
+	return s.g.Pix[s.g.PixOffset(x, y)]
+}
+
+// fold maps the index i into [0, n) by the border.
+func (s sampler) fold(i, n int) int {
+	switch s.border {
+	case Reflect:
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i
+	case Wrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// cubic is the Keys cubic convolution kernel with a = -0.5.
+func cubic(t float64) float64 {
+	t = math.Abs(t)
+	switch {
+	case t < 1:
+		return (1.5*t-2.5)*t*t + 1
+	case t < 2:
+		return ((-0.5*t+2.5)*t-4)*t + 2
+	}
+	return 0
+}
+
+// saturate rounds v to the nearest uint16.
+func saturate(v float64) uint16 {
+	switch {
+	case !(v > 0):
+		return 0
+	case v >= math.MaxUint16:
+		return math.MaxUint16
+	}
+	return uint16(v + 0.5)
+}
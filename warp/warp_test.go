@@ -0,0 +1,172 @@
+package warp
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/piersy/hough-go/gray16"
+)
+
+// grid returns an image of the given bounds whose values are given in row
+// order.
+func grid(r image.Rectangle, values ...uint16) *gray16.Gray16 {
+	g := gray16.NewGray16(r)
+	copy(g.Pix, values)
+	g.UpdateRange()
+	return g
+}
+
+func expect(t *testing.T, name string, g *gray16.Gray16, r image.Rectangle, values ...uint16) {
+	t.Helper()
+	if g.Rect != r {
+		t.Errorf("%s: expecting bounds %v got %v", name, r, g.Rect)
+		return
+	}
+	for i, e := range values {
+		if g.Pix[i] != e {
+			t.Errorf("%s: expecting %v got %v", name, values, g.Pix)
+			return
+		}
+	}
+	if min, max := g.Range(); g.MinVal != min || g.MaxVal != max {
+		t.Errorf("%s: expecting the range to be tracked", name)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	m := Translate(3, -2).Mul(Rotation(0.3)).Mul(Scale(2, 0.5))
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatal("Expecting an invertible matrix")
+	}
+	x, y := inv.Mul(m).Apply(5, 7)
+	if math.Abs(x-5) > 1e-9 || math.Abs(y-7) > 1e-9 {
+		t.Errorf("Expecting the inverse to undo the transform got (%g, %g)", x, y)
+	}
+	if x, y := Rotation(math.Pi/2).Apply(1, 0); math.Abs(x) > 1e-9 || math.Abs(y-1) > 1e-9 {
+		t.Errorf("Expecting a clockwise rotation to take right to down got (%g, %g)", x, y)
+	}
+	if _, ok := Scale(0, 1).Invert(); ok {
+		t.Error("Expecting a singular matrix")
+	}
+}
+
+func TestResize(t *testing.T) {
+	g := grid(image.Rect(5, 5, 9, 7),
+		0, 100, 200, 300,
+		400, 500, 600, 700)
+	area, err := Resize(g, 2, 1, Interpolate(Area))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "area", area, image.Rect(0, 0, 2, 1), 250, 450)
+
+	nearest, err := Resize(g, 8, 2, Interpolate(Nearest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "nearest", nearest, image.Rect(0, 0, 8, 2),
+		0, 0, 100, 100, 200, 200, 300, 300)
+
+	// Resizing a constant image leaves it constant however it is sampled.
+	c := grid(image.Rect(0, 0, 5, 3), 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000, 9000)
+	for _, i := range []Interpolation{Nearest, Bilinear, Bicubic, Area} {
+		out, err := Resize(c, 7, 2, Interpolate(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.MinVal != 9000 || out.MaxVal != 9000 {
+			t.Errorf("Interpolation %d: expecting a constant image got %v", i, out.Pix)
+		}
+	}
+
+	// Linear interpolation between the pixel centres, the centre of pixel 3
+	// of the result falls a quarter of the way from pixel 1 to 2.
+	bilinear, err := Resize(g, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := bilinear.Gray16At(3, 0).Y; v != 125 {
+		t.Errorf("Expecting 125 got %d", v)
+	}
+
+	if _, err := Resize(g, 0, 1); err == nil {
+		t.Error("Expecting an error for an empty size")
+	}
+	if _, err := Resize(g, 1, 1, Interpolate(Interpolation(9))); err == nil {
+		t.Error("Expecting an error for an invalid interpolation")
+	}
+}
+
+func TestFlip(t *testing.T) {
+	g := grid(image.Rect(1, 2, 4, 4),
+		1, 2, 3,
+		4, 5, 6)
+	expect(t, "horizontal", FlipHorizontal(g), g.Rect, 3, 2, 1, 6, 5, 4)
+	expect(t, "vertical", FlipVertical(g), g.Rect, 4, 5, 6, 1, 2, 3)
+	expect(t, "transpose", Transpose(g), image.Rect(2, 1, 4, 4), 1, 4, 2, 5, 3, 6)
+}
+
+func TestRotate(t *testing.T) {
+	g := grid(image.Rect(0, 0, 3, 2),
+		1, 2, 3,
+		4, 5, 6)
+	out, err := Rotate(g, math.Pi/2, Expand, Interpolate(Nearest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "expand", out, image.Rect(0, 0, 2, 3), 4, 1, 5, 2, 6, 3)
+
+	sq := gray16.NewGray16(image.Rect(0, 0, 5, 5))
+	for i := range sq.Pix {
+		sq.Pix[i] = uint16(i + 1)
+	}
+	out, err = Rotate(sq, math.Pi/4, Crop, Interpolate(Nearest), Fill(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Rect != sq.Rect || out.Gray16At(2, 2).Y != 13 || out.Gray16At(0, 0).Y != 100 {
+		t.Errorf("Expecting the centre kept and the corners filled got %v", out.Pix)
+	}
+	if _, err := Rotate(sq, 1, Crop, Interpolate(Area)); err == nil {
+		t.Error("Expecting an error for area interpolation")
+	}
+}
+
+func TestBorders(t *testing.T) {
+	g := grid(image.Rect(0, 0, 3, 1), 10, 20, 30)
+	for _, c := range []struct {
+		border   Border
+		expected []uint16
+	}{
+		{Constant, []uint16{7, 7, 10, 20, 30, 7, 7}},
+		{Replicate, []uint16{10, 10, 10, 20, 30, 30, 30}},
+		{Reflect, []uint16{20, 10, 10, 20, 30, 30, 20}},
+		{Wrap, []uint16{20, 30, 10, 20, 30, 10, 20}},
+	} {
+		out, err := Affine(g, Translate(2, 0), image.Rect(0, 0, 7, 1), Interpolate(Nearest), Borders(c.border), Fill(7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(t, "border", out, image.Rect(0, 0, 7, 1), c.expected...)
+	}
+	if _, err := Affine(g, Scale(1, 0), g.Rect); err == nil {
+		t.Error("Expecting an error for a singular transform")
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	g := gray16.NewGray16(image.Rect(2, 2, 2, 5))
+	for _, b := range []Border{Constant, Replicate, Reflect, Wrap} {
+		if _, err := Rotate(g, 0.5, Expand, Borders(b)); err == nil {
+			t.Errorf("Border %d: expecting an error rotating an empty image", b)
+		}
+		if _, err := Affine(g, Identity, image.Rect(0, 0, 3, 3), Borders(b)); err == nil {
+			t.Errorf("Border %d: expecting an error warping an empty image", b)
+		}
+		if _, err := Resize(g, 3, 3, Borders(b)); err == nil {
+			t.Errorf("Border %d: expecting an error resizing an empty image", b)
+		}
+	}
+}